@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ahmadfudl/gator/internal/database"
+)
+
+// lease_duration is how long a claimed feed stays reserved for the
+// aggregator that claimed it, it must outlast the slowest fetch.
+const lease_duration = 15 * time.Minute
+
+// scrapeResult is the outcome of scraping one feed.
+type scrapeResult struct {
+	url         string
+	title       string
+	link        string
+	added       int
+	updated     int
+	notModified bool
+	// set when the feed wasn't fetched, it's due again then
+	deferred time.Time
+	err      error
+}
+
+func (r scrapeResult) print() {
+	switch {
+	case r.err != nil:
+		fmt.Fprintf(os.Stderr, "gator: %s: %v\n", r.url, r.err)
+	case r.notModified:
+		fmt.Printf("channel:\n\turl: %s\n\tnot modified\n", r.url)
+	case !r.deferred.IsZero():
+		fmt.Printf("channel:\n\turl: %s\n\tdeferred until %s\n",
+			r.url, r.deferred.Format(time.TimeOnly))
+	default:
+		fmt.Printf("channel:\n\ttitle: %s\n\tlink: %s\n\tnew posts: %d\n\tupdated posts: %d\n",
+			r.title, r.link, r.added, r.updated)
+	}
+}
+
+// aggSummary tallies the results of an aggregator run, it's safe for
+// concurrent use by the workers.
+type aggSummary struct {
+	mu          sync.Mutex
+	fetched     int
+	notModified int
+	deferred    int
+	failed      int
+	added       int
+	updated     int
+}
+
+func (a *aggSummary) add(r scrapeResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !r.deferred.IsZero() {
+		a.deferred++
+		return
+	}
+
+	a.fetched++
+	if r.err != nil {
+		a.failed++
+	} else if r.notModified {
+		a.notModified++
+	}
+	a.added += r.added
+	a.updated += r.updated
+}
+
+func (a *aggSummary) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return fmt.Sprintf("fetched %d feeds (%d not modified, %d failed), %d deferred, %d new posts, %d updated posts",
+		a.fetched, a.notModified, a.failed, a.deferred, a.added, a.updated)
+}
+
+// aggOptions configure an aggregator run.
+type aggOptions struct {
+	// workers is the number of feeds fetched concurrently
+	workers int
+	// poll is how often due feeds are looked for
+	poll time.Duration
+	// drain is how long in-flight fetches may take to finish on shutdown
+	drain time.Duration
+	// once stops the run after the feeds due at its start are fetched
+	once bool
+}
+
+// scrapeFeeds runs the aggregator until ctx is cancelled, or until there are
+// no due feeds left with opts.once: every opts.poll it claims the feeds whose
+// next fetch has come and hands them to opts.workers concurrent fetchers, so
+// each feed is refreshed on its own schedule. On cancellation the in-flight
+// fetches get up to opts.drain to finish before they're cancelled too.
+// With opts.once, failing to claim the due feeds ends the run with an error.
+func scrapeFeeds(ctx context.Context, s *state, opts aggOptions) (*aggSummary, error) {
+	fetch_ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	summary := &aggSummary{}
+	feeds := make(chan database.Feed)
+	var wg sync.WaitGroup
+	for range opts.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range feeds {
+				r := scrapeFeed(fetch_ctx, s, f)
+				summary.add(r)
+				r.print()
+			}
+		}()
+	}
+
+	err := claimFeeds(ctx, fetch_ctx, s, feeds, opts)
+	close(feeds)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	// the in-flight fetches are only timed once the run is cancelled, a run
+	// that ran out of due feeds waits for them however long they take
+	select {
+	case <-done:
+	case <-ctx.Done():
+		select {
+		case <-done:
+		case <-time.After(opts.drain):
+			fmt.Fprintf(os.Stderr, "gator: drain timeout, cancelling in-flight fetches\n")
+			cancel()
+			<-done
+		}
+	}
+
+	return summary, err
+}
+
+// claimFeeds sends due feeds to the workers until ctx is cancelled, feeds
+// that were claimed but never handed out are released with release_ctx.
+func claimFeeds(ctx, release_ctx context.Context, s *state, feeds chan<- database.Feed,
+	opts aggOptions) error {
+	ticker := time.NewTicker(opts.poll)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		now := time.Now()
+		due, err := s.db.ClaimDueFeeds(ctx,
+			database.ClaimDueFeedsParams{
+				LeaseUntil: now.Add(lease_duration),
+				Now:        now,
+				MaxFeeds:   int32(opts.workers),
+			})
+		if err != nil && ctx.Err() == nil {
+			if opts.once {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		}
+
+		// blocks while all the workers are busy, so no more than workers
+		// claimed feeds are ever waiting
+		for i, f := range due {
+			select {
+			case feeds <- f:
+			case <-ctx.Done():
+				releaseFeeds(release_ctx, s, due[i:])
+				return nil
+			}
+		}
+
+		// a full claim means there are probably more due feeds, go get
+		// them as soon as a worker is free
+		if len(due) < opts.workers {
+			if opts.once {
+				return nil
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	return nil
+}
+
+// releaseFeeds gives up the lease on feeds so other aggregators don't have
+// to wait for it to expire.
+func releaseFeeds(ctx context.Context, s *state, feeds []database.Feed) {
+	for _, f := range feeds {
+		if err := s.db.ReleaseFeed(ctx, f.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const atomNS = "http://www.w3.org/2005/Atom"
+
+type (
+	atomFeed struct {
+		Title    atomText    `xml:"title"`
+		Subtitle atomText    `xml:"subtitle"`
+		Links    []atomLink  `xml:"link"`
+		Entries  []atomEntry `xml:"entry"`
+	}
+	atomLink struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	}
+	atomEntry struct {
+		ID        string     `xml:"id"`
+		Title     atomText   `xml:"title"`
+		Links     []atomLink `xml:"link"`
+		Summary   atomText   `xml:"summary"`
+		Content   atomText   `xml:"content"`
+		Updated   string     `xml:"updated"`
+		Published string     `xml:"published"`
+	}
+	// atomText is an Atom text construct, its body is either plain text,
+	// escaped html or inline xhtml depending on the type attribute.
+	atomText struct {
+		Type  string `xml:"type,attr"`
+		Text  string `xml:",chardata"`
+		Inner string `xml:",innerxml"`
+	}
+)
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(unwrapDiv(t.Inner))
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// unwrapDiv returns the contents of the <div> inline xhtml is wrapped in,
+// which per RFC 4287 isn't part of the text itself.
+func unwrapDiv(inner string) string {
+	inner = strings.TrimSpace(inner)
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	tok, err := dec.Token()
+	if err != nil {
+		return inner
+	}
+	se, ok := tok.(xml.StartElement)
+	if !ok || se.Name.Local != "div" {
+		return inner
+	}
+
+	start := int(dec.InputOffset())
+	end := strings.LastIndex(inner, "</")
+	if end < start {
+		// <div/>
+		return ""
+	}
+	return inner[start:end]
+}
+
+// alternate returns the href of the rel="alternate" link, a link without
+// a rel attribute is an alternate one per RFC 4287.
+func alternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+func (af *atomFeed) to_feed() *Feed {
+	feed := &Feed{
+		Channel: Channel{
+			Title:       af.Title.String(),
+			Link:        Link{Href: alternate(af.Links)},
+			Description: af.Subtitle.String(),
+		},
+	}
+
+	for _, e := range af.Entries {
+		item := Item{
+			Title:       e.Title.String(),
+			Link:        alternate(e.Links),
+			Description: e.Summary.String(),
+			PubDate:     strings.TrimSpace(e.Published),
+			Guid:        strings.TrimSpace(e.ID),
+		}
+		if item.Description == "" {
+			item.Description = e.Content.String()
+		}
+		if item.PubDate == "" {
+			item.PubDate = strings.TrimSpace(e.Updated)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	return feed
+}
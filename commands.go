@@ -1,14 +1,19 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ahmadfudl/gator/internal/config"
 	"github.com/ahmadfudl/gator/internal/database"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -23,7 +28,7 @@ type (
 		m map[string]handler
 	}
 	handler struct {
-		f func(*state, command) error
+		f func(context.Context, *state, command) error
 		d string
 	}
 )
@@ -32,18 +37,29 @@ func (c *commands) register(name string, h handler) {
 	c.m[name] = h
 }
 
-func (c *commands) run(s *state, cmd command) error {
+func (c *commands) run(ctx context.Context, s *state, cmd command) error {
 	if handler, ok := c.m[cmd.name]; !ok {
 		return fmt.Errorf(
 			"gator: '%s' is not a gator command. See gator help.",
 			cmd.name,
 		)
 	} else {
-		return handler.f(s, cmd)
+		return handler.f(ctx, s, cmd)
 	}
 }
 
-func _migrate(s *state, cmd command) error {
+// confirm asks the user a yes or no question, anything but yes is a no.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func _migrate(ctx context.Context, s *state, cmd command) error {
 	command := "up"
 	if len(cmd.args) > 1 {
 		return fmt.Errorf(`fatal: You must provide only a command for %s.
@@ -62,9 +78,9 @@ Usage: gator %s [<up> | <reset>]`,
 
 	var err error
 	if command == "up" {
-		_, err = s.prov.Up(context.Background())
+		_, err = s.prov.Up(ctx)
 	} else {
-		_, err = s.prov.DownTo(context.Background(), 0)
+		_, err = s.prov.DownTo(ctx, 0)
 	}
 	if err != nil {
 		return fmt.Errorf("gator: %v", err)
@@ -73,7 +89,7 @@ Usage: gator %s [<up> | <reset>]`,
 	return nil
 }
 
-func _login(s *state, cmd command) error {
+func _login(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) > 1 {
 		return fmt.Errorf(`fatal: You must provide only a username for %s.
 
@@ -87,7 +103,7 @@ Usage: gator %[1]s <username>`,
 	}
 
 	username := strings.ToLower(cmd.args[0])
-	u, err := s.db.GetUser(context.Background(), username)
+	u, err := s.db.GetUser(ctx, username)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf(`fatal: User '%s' not registered.
@@ -107,7 +123,7 @@ Usage: gator register '%[1]s'`,
 	return nil
 }
 
-func _register(s *state, cmd command) error {
+func _register(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) > 1 {
 		return fmt.Errorf(`fatal: You must provide only a username for %s.
 
@@ -121,7 +137,7 @@ Usage: gator %[1]s <username>`,
 	}
 
 	username := strings.ToLower(cmd.args[0])
-	u, err := s.db.CreateUser(context.Background(),
+	u, err := s.db.CreateUser(ctx,
 		database.CreateUserParams{
 			ID:        uuid.New(),
 			CreatedAt: time.Now(),
@@ -152,15 +168,43 @@ Usage: gator %[1]s <username>`,
 	return nil
 }
 
-func _reset(s *state, cmd command) error {
-	if len(cmd.args) != 0 {
+func _reset(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	all := fs.Bool("all", false, "")
+	username := fs.String("user", "", "")
+	yes := fs.Bool("yes", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--yes] --all | --user <username>`,
+			err, cmd.name)
+	}
+	if fs.NArg() != 0 {
 		return fmt.Errorf(`fatal: Too mangy args.
 
-Usage: gator %s`,
+Usage: gator %s [--yes] --all | --user <username>`,
+			cmd.name)
+	}
+	// wiping everything used to be what a bare reset did, it has to be asked
+	// for now
+	if *all == (*username != "") {
+		return fmt.Errorf(`fatal: You must provide either --all or --user for %s.
+
+Usage: gator %[1]s [--yes] --all | --user <username>`,
 			cmd.name)
 	}
 
-	if err := s.db.DeleteUsers(context.Background()); err != nil {
+	if *username != "" {
+		return resetUser(ctx, s, strings.ToLower(*username), *yes)
+	}
+
+	fmt.Println("Resetting deletes every user along with their feeds, follows, folders and posts.")
+	if !*yes && !confirm("Reset everything?") {
+		return fmt.Errorf("gator: Nothing was reset.")
+	}
+
+	if err := s.db.DeleteUsers(ctx); err != nil {
 		return fmt.Errorf("gator: %w", err)
 	}
 
@@ -170,7 +214,179 @@ Usage: gator %s`,
 	return nil
 }
 
-func _users(s *state, cmd command) error {
+// resetUser deletes everything username has, the feeds they added included,
+// but keeps the user.
+func resetUser(ctx context.Context, s *state, username string, yes bool) error {
+	u, err := s.db.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: User '%s' not registered.
+
+Usage: gator users`,
+				username)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	stats, err := s.db.GetUserStats(ctx, u.ID)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	fmt.Printf("Resetting '%s' deletes their %d follows, %d folders and the %d feeds they added, followed by %d other users.\n",
+		u.Name, stats.Follows, stats.Folders, stats.Feeds, stats.OtherFollows)
+	if !yes && !confirm("Reset them?") {
+		return fmt.Errorf("gator: Nothing was reset.")
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
+
+	if err := q.DeleteFeedFollowsForUser(ctx, u.ID); err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	if err := q.DeleteFoldersForUser(ctx, u.ID); err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	if err := q.DeleteFeedsForUser(ctx, u.ID); err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _user(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a command for %s.
+
+Usage: gator %[1]s rm [--yes] <username>
+       gator %[1]s rename <username> <new_username>`,
+			cmd.name)
+	}
+
+	sub := command{name: cmd.name + " " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "rm":
+		return _user_rm(ctx, s, sub)
+	case "rename":
+		return _user_rename(ctx, s, sub)
+	}
+
+	return fmt.Errorf(`fatal: Unknow command '%s'.
+
+Usage: gator %s rm | rename`,
+		cmd.args[0], cmd.name)
+}
+
+func _user_rm(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	yes := fs.Bool("yes", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--yes] <username>`,
+			err, cmd.name)
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf(`fatal: You must provide a username for %s.
+
+Usage: gator %[1]s [--yes] <username>`,
+			cmd.name)
+	}
+
+	username := strings.ToLower(fs.Arg(0))
+	u, err := s.db.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: User '%s' not registered.
+
+Usage: gator users`,
+				username)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	stats, err := s.db.GetUserStats(ctx, u.ID)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	// feeds go with the user who added them
+	fmt.Printf("Removing '%s' deletes their %d follows, %d folders and the %d feeds they added, followed by %d other users.\n",
+		u.Name, stats.Follows, stats.Folders, stats.Feeds, stats.OtherFollows)
+	if !*yes && !confirm("Remove them?") {
+		return fmt.Errorf("gator: Nothing was removed.")
+	}
+
+	if err := s.db.DeleteUser(ctx, u.ID); err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	if u.Name == s.cfg.Current_user_name {
+		if err := s.cfg.SetUser(""); err != nil {
+			return fmt.Errorf("gator: %w", err)
+		}
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _user_rename(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf(`fatal: You must provide a username and a new username for %s.
+
+Usage: gator %[1]s <username> <new_username>`,
+			cmd.name)
+	}
+	username, new_username := strings.ToLower(cmd.args[0]), strings.ToLower(cmd.args[1])
+	if new_username == "" {
+		return fmt.Errorf("gator: Can't have empty username.")
+	}
+
+	n, err := s.db.RenameUser(ctx,
+		database.RenameUserParams{
+			NewName:   new_username,
+			UpdatedAt: time.Now(),
+			Name:      username,
+		})
+	if err != nil {
+		// 23505 unique_violation
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return fmt.Errorf(
+				"fatal: User '%s' is already registered.",
+				new_username)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf(`fatal: User '%s' not registered.
+
+Usage: gator users`,
+			username)
+	}
+
+	if username == s.cfg.Current_user_name {
+		if err := s.cfg.SetUser(new_username); err != nil {
+			return fmt.Errorf("gator: %w", err)
+		}
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _users(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) != 0 {
 		return fmt.Errorf(`fatal: Too mangy args.
 
@@ -178,7 +394,7 @@ Usage: gator %s`,
 			cmd.name)
 	}
 
-	us, err := s.db.GetUsers(context.Background())
+	us, err := s.db.GetUsers(ctx)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil
@@ -197,47 +413,176 @@ Usage: gator %s`,
 	return nil
 }
 
-func _agg(s *state, cmd command) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf(`fatal: You must provide a between-requests' time for %s.
+func _agg(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts := aggOptions{}
+	fs.IntVar(&opts.workers, "workers", 4, "")
+	fs.DurationVar(&opts.poll, "poll", time.Minute, "")
+	interval := fs.Duration("interval", 0, "")
+	fs.DurationVar(&opts.drain, "drain", 30*time.Second, "")
+	fs.BoolVar(&opts.once, "once", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--once] [--workers <n>] [--poll <time_between_polls>] [--interval <time_between_fetches>] [--drain <timeout>]`,
+			err, cmd.name)
+	}
+
+	// the time between polls used to be the only, positional, argument
+	if fs.NArg() > 1 {
+		return fmt.Errorf(`fatal: Too mangy args.
 
-Usage: gator %[1]s <time_between_requests>`,
+Usage: gator %s [--once] [--workers <n>] [--poll <time_between_polls>] [--interval <time_between_fetches>] [--drain <timeout>]`,
 			cmd.name)
+	} else if fs.NArg() == 1 {
+		d, err := time.ParseDuration(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("gator: %w", err)
+		}
+		opts.poll = d
+	}
+
+	if opts.workers < 1 {
+		return fmt.Errorf("gator: Can't have less than one worker.")
 	}
-	time_between_req, err := time.ParseDuration(cmd.args[0])
+	if opts.poll <= 0 {
+		return fmt.Errorf("gator: Can't have a non positive poll interval.")
+	}
+	if *interval < 0 {
+		return fmt.Errorf("gator: Can't have a negative interval.")
+	}
+	if opts.drain < 0 {
+		return fmt.Errorf("gator: Can't have a negative drain timeout.")
+	}
+	// a fixed interval refreshes every feed as often, whatever its cadence
+	if *interval > 0 {
+		s.cfg.Min_interval = config.Duration(*interval)
+		s.cfg.Max_interval = config.Duration(*interval)
+	}
+	if opts.once {
+		fmt.Printf("Collecting the due feeds with %d workers\n", opts.workers)
+	} else {
+		fmt.Printf("Collecting due feeds every %v with %d workers\n",
+			opts.poll, opts.workers)
+	}
+	if *interval > 0 {
+		fmt.Printf("Fetching each feed every %v\n", *interval)
+	}
+
+	go func() {
+		<-ctx.Done()
+		fmt.Printf("Shutting down, waiting up to %v for in-flight fetches\n", opts.drain)
+	}()
+
+	summary, err := scrapeFeeds(ctx, s, opts)
+	fmt.Printf("gator: %v\n", summary)
+
+	// let cron and systemd know something went wrong
 	if err != nil {
 		return fmt.Errorf("gator: %w", err)
 	}
-	fmt.Println("Collecting feeds every", time_between_req)
-
-	ticker := time.NewTicker(time_between_req)
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+	if opts.once && summary.failed > 0 {
+		return fmt.Errorf("gator: %d feeds failed.", summary.failed)
 	}
+
+	return nil
 }
 
-func _addfeed(s *state, cmd command) error {
-	if len(cmd.args) > 2 {
-		return fmt.Errorf(`fatal: You must provide only a name and a url for %s.
+func _fetch(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf(`fatal: You must provide only a url for %s.
 
-Usage: gator %[1]s <name> <url>`,
+Usage: gator %[1]s <url>`,
 			cmd.name)
-	} else if len(cmd.args) < 2 {
-		return fmt.Errorf(`fatal: You must provide a name and a url for %s.
+	} else if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a url for %s.
 
-Usage: gator %[1]s <name> <url>`,
+Usage: gator %[1]s <url>`,
 			cmd.name)
 	}
+
+	url, key, err := feedURL(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	f, err := s.db.ClaimFeed(ctx, database.ClaimFeedParams{
+		LeaseUntil: now.Add(lease_duration),
+		Now:        now,
+		UrlKey:     key,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("gator: %w", err)
+		}
+		if _, err := s.db.GetFeed(ctx, key); err == nil {
+			return fmt.Errorf("fatal: Feed is being fetched by an aggregator, try again later.")
+		}
+		return fmt.Errorf(`fatal: Feed doesn't exist.
+
+Usage: gator addfeed <name> <url>`)
+	}
+
+	r := scrapeFeed(ctx, s, f)
+	r.print()
+	if r.err != nil {
+		return fmt.Errorf("gator: Fetching '%s' failed.", url)
+	}
+	if !r.deferred.IsZero() {
+		return fmt.Errorf("gator: '%s' wasn't fetched, try again later.", url)
+	}
+
+	return nil
+}
+
+func _addfeed(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	no_verify := fs.Bool("no-verify", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--no-verify] [<name>] <url>`,
+			err, cmd.name)
+	}
+
 	// validate the feed url and name
-	feed_name, feed_url := cmd.args[0], cmd.args[1]
-	if feed_name == "" {
-		return fmt.Errorf("gator: Can't have empty feed name.")
+	var feed_name, feed_url string
+	switch fs.NArg() {
+	case 1:
+		feed_url = fs.Arg(0)
+	case 2:
+		feed_name, feed_url = fs.Arg(0), fs.Arg(1)
+		if feed_name == "" {
+			return fmt.Errorf("gator: Can't have empty feed name.")
+		}
+	case 0:
+		return fmt.Errorf(`fatal: You must provide a url for %s.
+
+Usage: gator %[1]s [--no-verify] [<name>] <url>`,
+			cmd.name)
+	default:
+		return fmt.Errorf(`fatal: You must provide only a name and a url for %s.
+
+Usage: gator %[1]s [--no-verify] [<name>] <url>`,
+			cmd.name)
 	}
 	if feed_url == "" {
 		return fmt.Errorf("gator: Can't have empty feed url.")
 	}
+	if feed_name == "" && *no_verify {
+		return fmt.Errorf(`fatal: You must provide a name for %s --no-verify.
+
+Usage: gator %[1]s --no-verify <name> <url>`,
+			cmd.name)
+	}
+	feed_url, feed_key, err := feedURL(feed_url)
+	if err != nil {
+		return err
+	}
 
-	u, err := s.db.GetUser(context.Background(), s.cfg.Current_user_name)
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf(`fatal: Login first to add feeds.
@@ -247,12 +592,67 @@ Usage: gator login <username>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
-	feed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+	// fetch the feed first, so a typo doesn't end up in the feeds agg fetches
+	var fetched *Feed
+	cache := cacheHeaders{}
+	if !*no_verify {
+		feed, resolved, err := resolveFeed(ctx, feed_url, &cache)
+		if err != nil {
+			var ce *feedCandidatesError
+			if errors.As(err, &ce) {
+				return fmt.Errorf(`fatal: '%s' links to several feeds, pick one of them:
+
+%s
+Usage: gator %s [<name>] <url>`,
+					feed_url, ce.list(), cmd.name)
+			}
+			if errors.Is(err, errNotAFeed) {
+				return fmt.Errorf(`fatal: '%s' isn't a feed (%v).
+
+Use gator %s --no-verify <name> <url> to add it anyway.`,
+					feed_url, err, cmd.name)
+			}
+			return fmt.Errorf(`fatal: Can't fetch '%s' (%v).
+
+Use gator %s --no-verify <name> <url> to add it anyway.`,
+				feed_url, err, cmd.name)
+		}
+		if resolved != feed_url {
+			fmt.Printf("gator: Found feed at %s\n", resolved)
+			feed_url, feed_key, err = feedURL(resolved)
+			if err != nil {
+				return err
+			}
+		}
+		if feed_name == "" {
+			feed_name = strings.TrimSpace(feed.Channel.Title)
+		}
+		if feed_name == "" {
+			return fmt.Errorf(`fatal: Feed has no title, you must provide a name for it.
+
+Usage: gator %s <name> <url>`,
+				cmd.name)
+		}
+		fetched = feed
+	}
+
+	// the same feed may already be stored under another url
+	if feed, err := s.db.GetFeed(ctx, feed_key); err == nil {
+		return fmt.Errorf(`fatal: Feed '%s' already exists at %s.
+
+Usage: gator follow <url>`,
+			feed.Name, feed.Url)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	feed, err := s.db.CreateFeed(ctx, database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Name:      feed_name,
 		Url:       feed_url,
+		UrlKey:    feed_key,
 		UserID:    u.ID,
 	})
 	if err != nil {
@@ -263,7 +663,7 @@ Usage: gator login <username>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
-	_, err = s.db.CreateFeedFollow(context.Background(),
+	_, err = s.db.CreateFeedFollow(ctx,
 		database.CreateFeedFollowParams{
 			ID:        uuid.New(),
 			CreatedAt: time.Now(),
@@ -285,10 +685,15 @@ Usage: gator login <username>`)
 	// debug print
 	fmt.Println(feed)
 
+	if fetched != nil {
+		added, _ := saveFeed(ctx, s, feed, fetched, cache)
+		fmt.Printf("gator: Imported %d posts\n", added)
+	}
+
 	return nil
 }
 
-func _feeds(s *state, cmd command) error {
+func _feeds(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) != 0 {
 		return fmt.Errorf(`fatal: Too mangy args.
 
@@ -296,7 +701,7 @@ Usage: gator %s`,
 			cmd.name)
 	}
 
-	feeds, err := s.db.GetFeeds(context.Background())
+	feeds, err := s.db.GetFeeds(ctx)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil
@@ -305,14 +710,63 @@ Usage: gator %s`,
 	}
 
 	for _, feed := range feeds {
-		fmt.Printf("feed name    : %s\nfeed url     : %s\nfeed creator : %s\n\n",
+		fmt.Printf("feed name    : %s\nfeed url     : %s\nfeed creator : %s\n",
 			feed.Name, feed.Url, feed.Creator)
+		if feed.DisabledAt.Valid {
+			fmt.Printf("feed status  : disabled since %v\nfeed error   : %s\n",
+				feed.DisabledAt.Time, feed.LastError.String)
+		} else if feed.FailureCount > 0 {
+			fmt.Printf("feed status  : failing (%d in a row, last at %v)\nfeed error   : %s\n",
+				feed.FailureCount, feed.LastErrorAt.Time, feed.LastError.String)
+		} else {
+			fmt.Printf("feed status  : ok\n")
+		}
+		if feed.MovedFrom.Valid {
+			fmt.Printf("moved from   : %s (%v)\n", feed.MovedFrom.String, feed.MovedAt.Time)
+		}
+		if !feed.DisabledAt.Valid {
+			if feed.NextFetchAt.Valid {
+				fmt.Printf("next fetch   : %v\n", feed.NextFetchAt.Time)
+			} else {
+				fmt.Printf("next fetch   : now\n")
+			}
+		}
+		fmt.Println()
 	}
 
 	return nil
 }
 
-func _follow(s *state, cmd command) error {
+func _feed(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a command for %s.
+
+Usage: gator %[1]s enable <url>
+       gator %[1]s rm [--yes] <url>
+       gator %[1]s rename <url> <new_name>
+       gator %[1]s set-url [--no-verify] <url> <new_url>`,
+			cmd.name)
+	}
+
+	sub := command{name: cmd.name + " " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "enable":
+		return _feed_enable(ctx, s, sub)
+	case "rm":
+		return _feed_rm(ctx, s, sub)
+	case "rename":
+		return _feed_rename(ctx, s, sub)
+	case "set-url":
+		return _feed_set_url(ctx, s, sub)
+	}
+
+	return fmt.Errorf(`fatal: Unknow command '%s'.
+
+Usage: gator %s enable | rm | rename | set-url`,
+		cmd.args[0], cmd.name)
+}
+
+func _feed_enable(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) > 1 {
 		return fmt.Errorf(`fatal: You must provide only a url for %s.
 
@@ -321,79 +775,489 @@ Usage: gator %[1]s <url>`,
 	} else if len(cmd.args) < 1 {
 		return fmt.Errorf(`fatal: You must provide a url for %s.
 
-Usage: gator %[1]s <username>`,
+Usage: gator %[1]s <url>`,
 			cmd.name)
 	}
 
-	url := cmd.args[0]
-	if url == "" {
-		return fmt.Errorf("gator: Can't have empty url.")
+	_, key, err := feedURL(cmd.args[0])
+	if err != nil {
+		return err
 	}
 
-	u, err := s.db.GetUser(context.Background(), s.cfg.Current_user_name)
+	n, err := s.db.EnableFeed(ctx,
+		database.EnableFeedParams{
+			UpdatedAt: time.Now(),
+			UrlKey:    key,
+		})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf(`fatal: Login first to follow a feed.
-
-Usage: gator login <username>`)
-		}
 		return fmt.Errorf("gator: %w", err)
 	}
+	if n == 0 {
+		return fmt.Errorf(`fatal: Feed doesn't exist.
+
+Usage: gator addfeed <name> <url>`)
+	}
 
-	feed, err := s.db.GetFeed(context.Background(), url)
+	fmt.Println("Done.")
+
+	return nil
+}
+
+// ownFeed returns the feed at url, as long as the current user is the one who
+// added it.
+func ownFeed(ctx context.Context, s *state, url string, action string) (database.Feed, error) {
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf(`fatal: Feed doesn't exist.
+			return database.Feed{}, fmt.Errorf(`fatal: Login first to %s a feed.
 
-Usage: gator addfeed <name> <url>`)
+Usage: gator login <username>`,
+				action)
 		}
-		return fmt.Errorf("gator: %w", err)
+		return database.Feed{}, fmt.Errorf("gator: %w", err)
 	}
 
-	ff, err := s.db.CreateFeedFollow(context.Background(),
-		database.CreateFeedFollowParams{
-			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			FeedID:    feed.ID,
-			UserID:    u.ID,
-		})
+	_, key, err := feedURL(url)
 	if err != nil {
-		// error code for unique constraint vioaltion
-		// 23505 unique_violation
-		// https://www.postgresql.org/docs/9.3/errcodes-appendix.html
-		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
-			return fmt.Errorf("fatal: You can't follow the same feed twice.")
+		return database.Feed{}, err
+	}
+	feed, err := s.db.GetFeed(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Feed{}, fmt.Errorf(`fatal: Feed doesn't exist.
+
+Usage: gator addfeed <name> <url>`)
 		}
-		return fmt.Errorf("gator: %w", err)
+		return database.Feed{}, fmt.Errorf("gator: %w", err)
 	}
 
-	fmt.Println("Done.")
-	fmt.Println("\tfeed:", ff.Feed)
-	fmt.Println("\tuser:", ff.User)
+	if feed.UserID != u.ID {
+		return database.Feed{}, fmt.Errorf("fatal: Only the user who added '%s' can %s it.",
+			feed.Name, action)
+	}
 
-	return nil
+	return feed, nil
 }
 
-func _unfollow(s *state, cmd command) error {
-	if len(cmd.args) > 1 {
-		return fmt.Errorf(`fatal: You must provide only a url for %s.
+func _feed_rm(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	yes := fs.Bool("yes", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
 
-Usage: gator %[1]s <url>`,
-			cmd.name)
-	} else if len(cmd.args) < 1 {
+Usage: gator %s [--yes] <url>`,
+			err, cmd.name)
+	}
+	if fs.NArg() != 1 {
 		return fmt.Errorf(`fatal: You must provide a url for %s.
 
-Usage: gator %[1]s <username>`,
+Usage: gator %[1]s [--yes] <url>`,
 			cmd.name)
 	}
 
-	url := cmd.args[0]
-	if url == "" {
-		return fmt.Errorf("gator: Can't have empty url.")
+	feed, err := ownFeed(ctx, s, fs.Arg(0), "remove")
+	if err != nil {
+		return err
 	}
 
-	u, err := s.db.GetUser(context.Background(), s.cfg.Current_user_name)
+	stats, err := s.db.GetFeedStats(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	// follows and posts go with the feed
+	fmt.Printf("Removing '%s' unfollows it for %d users and deletes its %d posts.\n",
+		feed.Name, stats.Followers, stats.Posts)
+	if !*yes && !confirm("Remove it?") {
+		return fmt.Errorf("gator: Nothing was removed.")
+	}
+
+	err = s.db.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _feed_rename(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf(`fatal: You must provide a url and a new name for %s.
+
+Usage: gator %[1]s <url> <new_name>`,
+			cmd.name)
+	}
+	name := strings.TrimSpace(cmd.args[1])
+	if name == "" {
+		return fmt.Errorf("gator: Can't have empty feed name.")
+	}
+
+	feed, err := ownFeed(ctx, s, cmd.args[0], "rename")
+	if err != nil {
+		return err
+	}
+
+	stats, err := s.db.GetFeedStats(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	err = s.db.RenameFeed(ctx,
+		database.RenameFeedParams{
+			Name:      name,
+			UpdatedAt: time.Now(),
+			ID:        feed.ID,
+		})
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	fmt.Printf("Done, '%s' is now '%s' for its %d followers.\n",
+		feed.Name, name, stats.Followers)
+
+	return nil
+}
+
+func _feed_set_url(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	no_verify := fs.Bool("no-verify", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--no-verify] <url> <new_url>`,
+			err, cmd.name)
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf(`fatal: You must provide a url and a new url for %s.
+
+Usage: gator %[1]s [--no-verify] <url> <new_url>`,
+			cmd.name)
+	}
+
+	feed, err := ownFeed(ctx, s, fs.Arg(0), "change the url of")
+	if err != nil {
+		return err
+	}
+
+	new_url, _, err := feedURL(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if !*no_verify {
+		_, new_url, err = resolveFeed(ctx, new_url, nil)
+		if err != nil {
+			var ce *feedCandidatesError
+			if errors.As(err, &ce) {
+				return fmt.Errorf(`fatal: '%s' links to several feeds, pick one of them:
+
+%s
+Usage: gator %s <url> <new_url>`,
+					fs.Arg(1), ce.list(), cmd.name)
+			}
+			return fmt.Errorf(`fatal: Can't fetch '%s' (%v).
+
+Use gator %s --no-verify <url> <new_url> to change it anyway.`,
+				fs.Arg(1), err, cmd.name)
+		}
+	}
+
+	// two feeds can't share a url, even written differently
+	new_url, new_key, err := feedURL(new_url)
+	if err != nil {
+		return err
+	}
+	if other, err := s.db.GetFeed(ctx, new_key); err == nil && other.ID != feed.ID {
+		return fmt.Errorf("fatal: Feed '%s' is already at %s.", other.Name, other.Url)
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	stats, err := s.db.GetFeedStats(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	err = s.db.SetFeedUrl(ctx,
+		database.SetFeedUrlParams{
+			Url:       new_url,
+			UrlKey:    new_key,
+			UpdatedAt: time.Now(),
+			ID:        feed.ID,
+		})
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	fmt.Printf("Done, '%s' is now fetched from %s for its %d followers, its %d posts are kept.\n",
+		feed.Name, new_url, stats.Followers, stats.Posts)
+
+	return nil
+}
+
+func _import(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a format for %s.
+
+Usage: gator %[1]s opml <file>`,
+			cmd.name)
+	}
+
+	sub := command{name: cmd.name + " " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "opml":
+		return _import_opml(ctx, s, sub)
+	}
+
+	return fmt.Errorf(`fatal: Unknow format '%s'.
+
+Usage: gator %s opml <file>`,
+		cmd.args[0], cmd.name)
+}
+
+func _import_opml(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf(`fatal: You must provide only a file for %s.
+
+Usage: gator %[1]s <file>`,
+			cmd.name)
+	} else if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a file for %s.
+
+Usage: gator %[1]s <file>`,
+			cmd.name)
+	}
+
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: Login first to import feeds.
+
+Usage: gator login <username>`)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	data, err := os.ReadFile(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	entries, err := parseOPML(data)
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	summary := importFeeds(ctx, s, u, entries)
+	fmt.Printf("gator: %v\n", summary)
+
+	return nil
+}
+
+func _export(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a format for %s.
+
+Usage: gator %[1]s opml [--all] [<file>]`,
+			cmd.name)
+	}
+
+	sub := command{name: cmd.name + " " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "opml":
+		return _export_opml(ctx, s, sub)
+	}
+
+	return fmt.Errorf(`fatal: Unknow format '%s'.
+
+Usage: gator %s opml [--all] [<file>]`,
+		cmd.args[0], cmd.name)
+}
+
+func _export_opml(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	all := fs.Bool("all", false, "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--all] [<file>]`,
+			err, cmd.name)
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf(`fatal: You must provide only a file for %s.
+
+Usage: gator %[1]s [--all] [<file>]`,
+			cmd.name)
+	}
+
+	var title string
+	var entries []opmlEntry
+	if *all {
+		feeds, err := s.db.GetFeeds(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("gator: %w", err)
+		}
+		title = "gator feeds"
+		for _, f := range feeds {
+			entries = append(entries, opmlEntry{
+				name:    f.Name,
+				url:     f.Url,
+				siteURL: f.SiteUrl.String,
+			})
+		}
+	} else {
+		u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf(`fatal: Login first to export your feeds.
+
+Usage: gator login <username>`)
+			}
+			return fmt.Errorf("gator: %w", err)
+		}
+		ffs, err := s.db.GetFeedFollowsForUser(ctx,
+			database.GetFeedFollowsForUserParams{
+				UserID: u.ID,
+			})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("gator: %w", err)
+		}
+		title = fmt.Sprintf("%s's feeds in gator", u.Name)
+		for _, ff := range ffs {
+			entries = append(entries, opmlEntry{
+				name:     ff.Feed,
+				url:      ff.Url,
+				siteURL:  ff.SiteUrl.String,
+				category: ff.Folder.String,
+			})
+		}
+	}
+
+	if fs.NArg() == 0 {
+		if err := writeOPML(os.Stdout, title, entries); err != nil {
+			return fmt.Errorf("gator: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	if err := writeOPML(f, title, entries); err != nil {
+		f.Close()
+		return fmt.Errorf("gator: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "gator: Exported %d feeds to %s\n", len(entries), fs.Arg(0))
+
+	return nil
+}
+
+func _follow(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf(`fatal: You must provide only a url for %s.
+
+Usage: gator %[1]s <url>`,
+			cmd.name)
+	} else if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a url for %s.
+
+Usage: gator %[1]s <username>`,
+			cmd.name)
+	}
+
+	if cmd.args[0] == "" {
+		return fmt.Errorf("gator: Can't have empty url.")
+	}
+	url, key, err := feedURL(cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: Login first to follow a feed.
+
+Usage: gator login <username>`)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	feed, err := s.db.GetFeed(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// url may be the page of a website that links to the feed
+		feed, err = discoverStoredFeed(ctx, s, url)
+		var ce *feedCandidatesError
+		if errors.As(err, &ce) {
+			return fmt.Errorf(`fatal: '%s' links to several feeds, pick one of them:
+
+%s
+Usage: gator %s <url>`,
+				url, ce.list(), cmd.name)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: Feed doesn't exist.
+
+Usage: gator addfeed <name> <url>`)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	ff, err := s.db.CreateFeedFollow(ctx,
+		database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			FeedID:    feed.ID,
+			UserID:    u.ID,
+		})
+	if err != nil {
+		// error code for unique constraint vioaltion
+		// 23505 unique_violation
+		// https://www.postgresql.org/docs/9.3/errcodes-appendix.html
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return fmt.Errorf("fatal: You can't follow the same feed twice.")
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	fmt.Println("Done.")
+	fmt.Println("\tfeed:", ff.Feed)
+	fmt.Println("\tuser:", ff.User)
+
+	return nil
+}
+
+func _unfollow(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf(`fatal: You must provide only a url for %s.
+
+Usage: gator %[1]s <url>`,
+			cmd.name)
+	} else if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a url for %s.
+
+Usage: gator %[1]s <username>`,
+			cmd.name)
+	}
+
+	if cmd.args[0] == "" {
+		return fmt.Errorf("gator: Can't have empty url.")
+	}
+	_, key, err := feedURL(cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf(`fatal: Login first to unfollow a feed.
@@ -403,7 +1267,7 @@ Usage: gator login <username>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
-	feed, err := s.db.GetFeed(context.Background(), url)
+	feed, err := s.db.GetFeed(ctx, key)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf(`fatal: feed doesn't exist.
@@ -413,7 +1277,7 @@ Usage: gator addfeed <name> <url>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
-	err = s.db.DeleteFeedFollow(context.Background(),
+	err = s.db.DeleteFeedFollow(ctx,
 		database.DeleteFeedFollowParams{
 			UserID: u.ID,
 			FeedID: feed.ID,
@@ -427,7 +1291,7 @@ Usage: gator addfeed <name> <url>`)
 	return nil
 }
 
-func _following(s *state, cmd command) error {
+func _folders(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.args) != 0 {
 		return fmt.Errorf(`fatal: Too mangy args.
 
@@ -435,7 +1299,249 @@ Usage: gator %s`,
 			cmd.name)
 	}
 
-	u, err := s.db.GetUser(context.Background(), s.cfg.Current_user_name)
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: Login first to see your folders.
+
+Usage: gator login <username>`)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	folders, err := s.db.GetFoldersForUser(ctx, u.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	for _, f := range folders {
+		fmt.Printf("* %s (%d feeds)\n", f.Name, f.Feeds)
+	}
+
+	return nil
+}
+
+func _folder(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a command for %s.
+
+Usage: gator %[1]s add <name>
+       gator %[1]s rename <name> <new_name>
+       gator %[1]s rm <name>
+       gator %[1]s move <url> [<name>]`,
+			cmd.name)
+	}
+
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: Login first to manage your folders.
+
+Usage: gator login <username>`)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	sub := command{name: cmd.name + " " + cmd.args[0], args: cmd.args[1:]}
+	switch cmd.args[0] {
+	case "add":
+		return _folder_add(ctx, s, u, sub)
+	case "rename":
+		return _folder_rename(ctx, s, u, sub)
+	case "rm":
+		return _folder_rm(ctx, s, u, sub)
+	case "move":
+		return _folder_move(ctx, s, u, sub)
+	}
+
+	return fmt.Errorf(`fatal: Unknow command '%s'.
+
+Usage: gator %s add | rename | rm | move`,
+		cmd.args[0], cmd.name)
+}
+
+func _folder_add(ctx context.Context, s *state, u database.User, cmd command) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf(`fatal: You must provide only a name for %s.
+
+Usage: gator %[1]s <name>`,
+			cmd.name)
+	} else if len(cmd.args) < 1 || cmd.args[0] == "" {
+		return fmt.Errorf(`fatal: You must provide a name for %s.
+
+Usage: gator %[1]s <name>`,
+			cmd.name)
+	}
+
+	_, err := s.db.CreateFolder(ctx,
+		database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      cmd.args[0],
+			UserID:    u.ID,
+		})
+	if err != nil {
+		// 23505 unique_violation
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return fmt.Errorf("fatal: Folder '%s' already exists.", cmd.args[0])
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _folder_rename(ctx context.Context, s *state, u database.User, cmd command) error {
+	if len(cmd.args) != 2 || cmd.args[1] == "" {
+		return fmt.Errorf(`fatal: You must provide a name and a new name for %s.
+
+Usage: gator %[1]s <name> <new_name>`,
+			cmd.name)
+	}
+
+	n, err := s.db.RenameFolder(ctx,
+		database.RenameFolderParams{
+			NewName:   cmd.args[1],
+			UpdatedAt: time.Now(),
+			UserID:    u.ID,
+			Name:      cmd.args[0],
+		})
+	if err != nil {
+		// 23505 unique_violation
+		if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+			return fmt.Errorf("fatal: Folder '%s' already exists.", cmd.args[1])
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf(`fatal: Folder '%s' doesn't exist.
+
+Usage: gator folders`,
+			cmd.args[0])
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _folder_rm(ctx context.Context, s *state, u database.User, cmd command) error {
+	if len(cmd.args) > 1 {
+		return fmt.Errorf(`fatal: You must provide only a name for %s.
+
+Usage: gator %[1]s <name>`,
+			cmd.name)
+	} else if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a name for %s.
+
+Usage: gator %[1]s <name>`,
+			cmd.name)
+	}
+
+	// the feeds in the folder are still followed, outside of any folder
+	n, err := s.db.DeleteFolder(ctx,
+		database.DeleteFolderParams{
+			UserID: u.ID,
+			Name:   cmd.args[0],
+		})
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf(`fatal: Folder '%s' doesn't exist.
+
+Usage: gator folders`,
+			cmd.args[0])
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _folder_move(ctx context.Context, s *state, u database.User, cmd command) error {
+	if len(cmd.args) > 2 {
+		return fmt.Errorf(`fatal: You must provide only a url and a folder for %s.
+
+Usage: gator %[1]s <url> [<name>]`,
+			cmd.name)
+	} else if len(cmd.args) < 1 {
+		return fmt.Errorf(`fatal: You must provide a url for %s.
+
+Usage: gator %[1]s <url> [<name>]`,
+			cmd.name)
+	}
+
+	_, key, err := feedURL(cmd.args[0])
+	if err != nil {
+		return err
+	}
+	feed, err := s.db.GetFeed(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf(`fatal: Feed doesn't exist.
+
+Usage: gator addfeed <name> <url>`)
+		}
+		return fmt.Errorf("gator: %w", err)
+	}
+
+	// without a folder the feed is taken out of the one it's in
+	name := ""
+	if len(cmd.args) == 2 {
+		name = cmd.args[1]
+	}
+	folder_id, err := findFolder(ctx, s, u.ID, name)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.db.SetFeedFollowFolder(ctx,
+		database.SetFeedFollowFolderParams{
+			FolderID:  folder_id,
+			UpdatedAt: time.Now(),
+			UserID:    u.ID,
+			FeedID:    feed.ID,
+		})
+	if err != nil {
+		return fmt.Errorf("gator: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf(`fatal: You don't follow '%s'.
+
+Usage: gator follow <url>`,
+			feed.Name)
+	}
+
+	fmt.Println("Done.")
+
+	return nil
+}
+
+func _following(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	folder := fs.String("folder", "", "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--folder <name>]`,
+			err, cmd.name)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf(`fatal: Too mangy args.
+
+Usage: gator %s [--folder <name>]`,
+			cmd.name)
+	}
+
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf(`fatal: Login first to see follow list.
@@ -445,7 +1551,16 @@ Usage: gator login <username>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
-	ffs, err := s.db.GetFeedFollowsForUser(context.Background(), u.ID)
+	folder_id, err := findFolder(ctx, s, u.ID, *folder)
+	if err != nil {
+		return err
+	}
+
+	ffs, err := s.db.GetFeedFollowsForUser(ctx,
+		database.GetFeedFollowsForUserParams{
+			UserID:   u.ID,
+			FolderID: folder_id,
+		})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil
@@ -453,32 +1568,48 @@ Usage: gator login <username>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
+	// follows come grouped by folder, the ones outside of any folder first
+	current := ""
 	for _, ff := range ffs {
+		if ff.Folder.String != current {
+			current = ff.Folder.String
+			fmt.Printf("== %s ==\n\n", current)
+		}
 		fmt.Printf("feed: %s\nurl:  %s\n\n", ff.Feed, ff.Url)
 	}
 
 	return nil
 }
 
-func _browse(s *state, cmd command) error {
+func _browse(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	folder := fs.String("folder", "", "")
+	if err := fs.Parse(cmd.args); err != nil {
+		return fmt.Errorf(`fatal: %v.
+
+Usage: gator %s [--folder <name>] [<limit>]`,
+			err, cmd.name)
+	}
+
 	limit := 2
-	if len(cmd.args) > 1 {
+	if fs.NArg() > 1 {
 		return fmt.Errorf(`fatal: You must provide only a limit for %s.
 
-Usage: gator %[1]s <url>`,
+Usage: gator %[1]s [--folder <name>] [<limit>]`,
 			cmd.name)
-	} else if len(cmd.args) == 1 {
-		int, err := strconv.Atoi(cmd.args[0])
+	} else if fs.NArg() == 1 {
+		int, err := strconv.Atoi(fs.Arg(0))
 		if err != nil {
 			return fmt.Errorf(`fatal: You must provide a limit for %s.
 
-Usage: gator %[1]s <limit>`,
+Usage: gator %[1]s [--folder <name>] [<limit>]`,
 				cmd.name)
 		}
 		limit = int
 	}
 
-	u, err := s.db.GetUser(context.Background(), s.cfg.Current_user_name)
+	u, err := s.db.GetUser(ctx, s.cfg.Current_user_name)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf(`fatal: Login first to browse feeds.
@@ -488,22 +1619,32 @@ Usage: gator login <username>`)
 		return fmt.Errorf("gator: %w", err)
 	}
 
-	posts, err := s.db.GetPostsUser(context.Background(),
+	folder_id, err := findFolder(ctx, s, u.ID, *folder)
+	if err != nil {
+		return err
+	}
+
+	posts, err := s.db.GetPostsUser(ctx,
 		database.GetPostsUserParams{
-			UserID: u.ID,
-			Limit:  int32(limit),
+			UserID:   u.ID,
+			FolderID: folder_id,
+			Limit:    int32(limit),
 		})
 
 	for i := range posts {
+		estimated := ""
+		if posts[i].PublishedAtEstimated {
+			estimated = " (estimated)"
+		}
 		fmt.Printf(`
 post:
 	title:       %s
 	link:        %s
-	pubDate:     %v
+	pubDate:     %v%s
 	description: %s
 `,
 			posts[i].Title, posts[i].Url,
-			posts[i].PublishedAt.Time, posts[i].Description.String)
+			posts[i].PublishedAt.Time, estimated, posts[i].Description.String)
 	}
 
 	return nil
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// date_layouts are tried in order by parseDate, after the weekday has been
+// stripped and the zone normalized. They cover RFC 822/1123 and its common
+// deviations (single digit days, two-digit years, missing seconds, long
+// month names) as well as RFC 3339 and the looser ISO 8601 forms.
+var date_layouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04:05 MST",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 06 15:04 MST",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04:05 MST",
+	"2 January 2006 15:04 -0700",
+	"2 January 2006 15:04 MST",
+	"2 Jan 2006 15:04:05",
+	"2 January 2006",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 15:04:05 MST 2006",
+	"January 2 2006",
+	"Jan 2 2006",
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// date_zones maps the zone names allowed by RFC 822 plus a few common
+// ones to their offsets, time.Parse only knows the local zone's names and
+// treats every other abbreviation as UTC. Dates in the zones left out, many
+// of them ambiguous, are read as UTC then: a few hours off still beats an
+// estimated date.
+var date_zones = map[string]string{
+	"UT":   "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"JST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+}
+
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// parseDate parses a feed item date in any of the formats in date_layouts.
+func parseDate(s string) (time.Time, error) {
+	normalized := normalizeDate(s)
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	for _, layout := range date_layouts {
+		t, err := time.Parse(layout, normalized)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("can't parse date '%s'", s)
+}
+
+// normalizeDate strips the things that make otherwise valid dates fail to
+// parse: commas, repeated spaces, the optional weekday, comments such as
+// "(UTC)", named zones and offsets with a colon in RFC 822 dates.
+func normalizeDate(s string) string {
+	if i := strings.IndexByte(s, '('); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.ReplaceAll(s, ",", " ")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	first := strings.ToLower(strings.TrimSuffix(fields[0], "."))
+	for _, wd := range weekdays {
+		if strings.HasPrefix(first, wd) {
+			fields = fields[1:]
+			break
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	last := fields[len(fields)-1]
+	if offset, ok := date_zones[strings.ToUpper(last)]; ok {
+		fields[len(fields)-1] = offset
+	} else if len(last) == 6 && (last[0] == '+' || last[0] == '-') && last[3] == ':' {
+		fields[len(fields)-1] = last[:3] + last[4:]
+	}
+
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// time.Parse knows the names of the local zone
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.UTC
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// RFC 822 and RFC 1123
+		{"Wed, 01 May 2024 10:00:00 +0000", "2024-05-01T10:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 -0700", "2024-05-01T17:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 GMT", "2024-05-01T10:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 UT", "2024-05-01T10:00:00Z"},
+		{"Wed, 01 May 2024 10:00 Z", "2024-05-01T10:00:00Z"},
+		// zone names
+		{"Wed, 01 May 2024 10:00:00 EDT", "2024-05-01T14:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 pst", "2024-05-01T18:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 CEST", "2024-05-01T08:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 BST", "2024-05-01T09:00:00Z"},
+		// zones not in date_zones, here an ambiguous one, are read as UTC
+		{"Wed, 01 May 2024 10:00:00 IST", "2024-05-01T10:00:00Z"},
+		// +hh:mm offsets
+		{"Wed, 01 May 2024 10:00:00 +02:00", "2024-05-01T08:00:00Z"},
+		{"01 May 2024 10:00 -03:30", "2024-05-01T13:30:00Z"},
+		// two-digit years
+		{"Wed, 01 May 24 10:00:00 +0000", "2024-05-01T10:00:00Z"},
+		{"1 May 24 10:00 EST", "2024-05-01T15:00:00Z"},
+		// missing or unusual weekdays
+		{"01 May 2024 10:00:00 +0000", "2024-05-01T10:00:00Z"},
+		{"Wednesday, 01 May 2024 10:00:00 +0000", "2024-05-01T10:00:00Z"},
+		{"Wed., 1 May 2024 10:00:00 +0000", "2024-05-01T10:00:00Z"},
+		// long month names, comments and stray spaces
+		{"1 May 2024", "2024-05-01T00:00:00Z"},
+		{"1 September 2024 10:00:00 +0000", "2024-09-01T10:00:00Z"},
+		{"Wed, 01 May 2024 10:00:00 +0000 (UTC)", "2024-05-01T10:00:00Z"},
+		{"  Wed,  01 May 2024   10:00:00 +0000 ", "2024-05-01T10:00:00Z"},
+		{"May 1 2024", "2024-05-01T00:00:00Z"},
+		{"Wed May 1 10:00:00 UTC 2024", "2024-05-01T10:00:00Z"},
+		// RFC 3339 and ISO 8601
+		{"2024-05-01T10:00:00Z", "2024-05-01T10:00:00Z"},
+		{"2024-05-01T10:00:00+02:00", "2024-05-01T08:00:00Z"},
+		{"2024-05-01T10:00:00.123Z", "2024-05-01T10:00:00.123Z"},
+		{"2024-05-01T10:00:00.5-01:00", "2024-05-01T11:00:00.5Z"},
+		{"2024-05-01T10:00:00+0200", "2024-05-01T08:00:00Z"},
+		{"2024-05-01T10:00Z", "2024-05-01T10:00:00Z"},
+		{"2024-05-01T10:00:00", "2024-05-01T10:00:00Z"},
+		{"2024-05-01T10:00", "2024-05-01T10:00:00Z"},
+		{"2024-05-01 10:00:00+02:00", "2024-05-01T08:00:00Z"},
+		{"2024-05-01 10:00:00", "2024-05-01T10:00:00Z"},
+		{"2024-05-01 10:00", "2024-05-01T10:00:00Z"},
+		{"2024-05-01", "2024-05-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		want, err := time.Parse(time.RFC3339, tt.want)
+		if err != nil {
+			t.Fatalf("bad test date %q: %v", tt.want, err)
+		}
+		got, err := parseDate(tt.in)
+		if err != nil {
+			t.Errorf("parseDate(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseDate(%q) = %v, want %v", tt.in, got.UTC(), want)
+		}
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"Wed,",
+		"yesterday",
+		"01/05/2024",
+		"2024-13-01",
+		"Wed, 32 May 2024 10:00:00 +0000",
+	} {
+		if got, err := parseDate(in); err == nil {
+			t.Errorf("parseDate(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Wed, 01 May 2024 10:00:00 GMT", "01 May 2024 10:00:00 GMT"},
+		{"Wed, 01 May 2024 10:00:00 EST", "01 May 2024 10:00:00 -0500"},
+		{"Wed, 01 May 2024 10:00:00 +02:00", "01 May 2024 10:00:00 +0200"},
+		{"Wed, 01 May 2024 10:00:00 +0000 (UTC)", "01 May 2024 10:00:00 +0000"},
+		{"2024-05-01T10:00:00+02:00", "2024-05-01T10:00:00+02:00"},
+		{"Wed", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeDate(tt.in); got != tt.want {
+			t.Errorf("normalizeDate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
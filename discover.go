@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ahmadfudl/gator/internal/database"
+)
+
+// feed_types are the <link rel="alternate"> types that point to a feed.
+var feed_types = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+	"application/rdf+xml",
+}
+
+// feed_paths are where sites that don't link to their feed usually put it.
+var feed_paths = []string{"/feed", "/rss.xml", "/atom.xml", "/index.xml"}
+
+// feedCandidatesError is returned by resolveFeed for a page that links to
+// more than one feed, the user has to pick one of them.
+type feedCandidatesError struct {
+	url        string
+	candidates []string
+}
+
+func (e *feedCandidatesError) Error() string {
+	return fmt.Sprintf("rss: %s links to %d feeds", e.url, len(e.candidates))
+}
+
+// list formats the candidates one per line, to be shown to the user.
+func (e *feedCandidatesError) list() string {
+	var b strings.Builder
+	for _, c := range e.candidates {
+		fmt.Fprintf(&b, "\t%s\n", c)
+	}
+	return b.String()
+}
+
+// resolveFeed fetches the feed at url. When url is a web page instead, the
+// feed is looked for in the page's <link rel="alternate"> tags and then at
+// the usual paths of the site. It returns the feed and the url it's at.
+func resolveFeed(ctx context.Context, url string, cache *cacheHeaders) (*Feed, string, error) {
+	feed, url, err := fetchFeedURL(ctx, url, cache)
+	var nfe *notFeedError
+	if err == nil || !errors.As(err, &nfe) {
+		return feed, url, err
+	}
+
+	candidates, feed := discoverFeeds(ctx, nfe, cache)
+	switch len(candidates) {
+	case 0:
+		return nil, "", err
+	case 1:
+		if feed != nil {
+			return feed, candidates[0], nil
+		}
+		return fetchFeedURL(ctx, candidates[0], cache)
+	}
+	return nil, "", &feedCandidatesError{url: url, candidates: candidates}
+}
+
+// fetchFeedURL is fetchFeed returning the url the feed is at, url itself
+// unless the feed has moved.
+func fetchFeedURL(ctx context.Context, url string, cache *cacheHeaders) (*Feed, string, error) {
+	feed, moved, err := fetchFeed(ctx, url, cache)
+	if err != nil {
+		return nil, url, err
+	}
+	if moved != "" {
+		url = moved
+	}
+	return feed, url, nil
+}
+
+// discoverStoredFeed returns the stored feed the page at url links to,
+// sql.ErrNoRows when there's none.
+func discoverStoredFeed(ctx context.Context, s *state, url string) (database.Feed, error) {
+	_, found, err := resolveFeed(ctx, url, nil)
+	if err != nil {
+		var ce *feedCandidatesError
+		if errors.As(err, &ce) {
+			return database.Feed{}, err
+		}
+		return database.Feed{}, sql.ErrNoRows
+	}
+	_, key, err := feedURL(found)
+	if err != nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	// url is a feed itself, one nobody added yet
+	if _, url_key, _ := feedURL(url); key == url_key {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	fmt.Printf("gator: Found feed at %s\n", found)
+	return s.db.GetFeed(ctx, key)
+}
+
+// discoverFeeds returns the urls of the feeds the HTML page of nfe links to
+// or, when it doesn't link to any, the first of the site's usual feed paths
+// that has a feed. That one has been fetched already, with cache, and its
+// feed is returned too.
+func discoverFeeds(ctx context.Context, nfe *notFeedError, cache *cacheHeaders) ([]string, *Feed) {
+	if !isHTML(nfe.body, nfe.contentType) {
+		return nil, nil
+	}
+	page, err := url.Parse(nfe.url)
+	if err != nil {
+		return nil, nil
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, href := range feedLinks(nfe.body) {
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		u, key, err := feedURL(page.ResolveReference(ref).String())
+		if err != nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, u)
+	}
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range feed_paths {
+		if ctx.Err() != nil {
+			break
+		}
+		u, _, err := feedURL(page.ResolveReference(&url.URL{Path: path}).String())
+		if err != nil {
+			continue
+		}
+		if feed, u, err := fetchFeedURL(ctx, u, cache); err == nil {
+			return []string{u}, feed
+		}
+	}
+
+	return nil, nil
+}
+
+func isHTML(data []byte, contentType string) bool {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt == "text/html" || mt == "application/xhtml+xml"
+	}
+	return strings.HasPrefix(http.DetectContentType(data), "text/html")
+}
+
+// feedLinks returns the hrefs of the page's <link rel="alternate"> tags that
+// point to a feed, relative to its <base> when it has one.
+// The page is tokenized leniently, it only has to make sense up to the links.
+func feedLinks(data []byte) []string {
+	dec := xml.NewDecoder(bytes.NewReader(quoteAttrs(data)))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var base *url.URL
+	var hrefs []string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(se.Name.Local) {
+		case "base":
+			if base == nil {
+				base, _ = url.Parse(attr(se, "href"))
+			}
+		case "link":
+			if !hasToken(attr(se, "rel"), "alternate") {
+				continue
+			}
+			mt, _, _ := mime.ParseMediaType(attr(se, "type"))
+			if !isFeedType(mt) {
+				continue
+			}
+			if href := strings.TrimSpace(attr(se, "href")); href != "" {
+				hrefs = append(hrefs, href)
+			}
+		case "body":
+			// feeds are linked from the head
+			return resolveAll(base, hrefs)
+		}
+	}
+
+	return resolveAll(base, hrefs)
+}
+
+var (
+	// a start tag, quoted attribute values may hold a >
+	html_tag = regexp.MustCompile(`<[a-zA-Z](?:[^>"']|"[^"]*"|'[^']*')*>`)
+	// quoted values are matched whole so the = inside them are left alone
+	attr_value = regexp.MustCompile(`"[^"]*"|'[^']*'|=\s*[^\s"'<>=][^\s"'<>]*`)
+)
+
+// quoteAttrs quotes the unquoted attribute values of the tags in data. HTML
+// allows them but encoding/xml stops reading one at the first character that
+// can't be in a name, such as the slash of a media type, and gives up on the
+// rest of the page.
+func quoteAttrs(data []byte) []byte {
+	return html_tag.ReplaceAllFunc(data, func(tag []byte) []byte {
+		return attr_value.ReplaceAllFunc(tag, func(v []byte) []byte {
+			if v[0] != '=' {
+				return v
+			}
+			return []byte(`="` + strings.TrimSpace(string(v[1:])) + `"`)
+		})
+	})
+}
+
+func resolveAll(base *url.URL, hrefs []string) []string {
+	if base == nil {
+		return hrefs
+	}
+	for i, href := range hrefs {
+		if ref, err := url.Parse(href); err == nil {
+			hrefs[i] = base.ResolveReference(ref).String()
+		}
+	}
+	return hrefs
+}
+
+func attr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFeedType(mt string) bool {
+	for _, t := range feed_types {
+		if mt == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestResolveFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		title       string
+		wantErr     error
+	}{
+		{
+			name:        "json feed",
+			contentType: "application/feed+json",
+			body:        `{"version": "https://jsonfeed.org/version/1.1", "title": "Example", "items": []}`,
+			title:       "Example",
+		},
+		{
+			name:        "json api",
+			contentType: "application/json",
+			body:        `{"status": "ok", "title": "Example"}`,
+			wantErr:     errNotAFeed,
+		},
+		{
+			name:        "empty json object",
+			contentType: "text/plain",
+			body:        `{}`,
+			wantErr:     errNotAFeed,
+		},
+	}
+
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", tt.contentType)
+			w.Write([]byte(tt.body))
+		}))
+
+		feed, url, err := resolveFeed(context.Background(), srv.URL, nil)
+		srv.Close()
+
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: resolveFeed() error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if url != srv.URL || feed.Channel.Title != tt.title {
+			t.Errorf("%s: resolveFeed() = %q at %q, want %q at %q",
+				tt.name, feed.Channel.Title, url, tt.title, srv.URL)
+		}
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "rss",
+			html: `<html><head><link rel="alternate" type="application/rss+xml" href="/feed.xml"></head></html>`,
+			want: []string{"/feed.xml"},
+		},
+		{
+			name: "every feed type",
+			html: `<head>
+				<link rel="alternate" type="application/rss+xml" href="/rss">
+				<link rel="alternate" type="application/atom+xml" href="/atom">
+				<link rel="alternate" type="application/feed+json" href="/json">
+				<link rel="alternate" type="application/rdf+xml" href="/rdf">
+			</head>`,
+			want: []string{"/rss", "/atom", "/json", "/rdf"},
+		},
+		{
+			name: "rel token lists",
+			html: `<head>
+				<link rel="alternate feed" type="application/atom+xml" href="/a">
+				<link rel=" ALTERNATE " type="application/atom+xml" href="/b">
+				<link rel="feed" type="application/atom+xml" href="/c">
+				<link rel="stylesheet alternate-ish" type="application/atom+xml" href="/d">
+			</head>`,
+			want: []string{"/a", "/b"},
+		},
+		{
+			name: "type parameters",
+			html: `<head><link rel="alternate" type="application/rss+xml; charset=utf-8" href="/feed"></head>`,
+			want: []string{"/feed"},
+		},
+		{
+			name: "non-feed types",
+			html: `<head>
+				<link rel="alternate" hreflang="fr" type="text/html" href="/fr/">
+				<link rel="alternate" type="application/json" href="/wp-json/">
+				<link rel="alternate" href="/print">
+				<link rel="alternate" type="application/rss+xml" href="/feed">
+			</head>`,
+			want: []string{"/feed"},
+		},
+		{
+			name: "base href",
+			html: `<head>
+				<base href="https://example.com/blog/">
+				<link rel="alternate" type="application/rss+xml" href="feed.xml">
+				<link rel="alternate" type="application/atom+xml" href="/atom.xml">
+				<link rel="alternate" type="application/atom+xml" href="https://feeds.example.org/x">
+			</head>`,
+			want: []string{
+				"https://example.com/blog/feed.xml",
+				"https://example.com/atom.xml",
+				"https://feeds.example.org/x",
+			},
+		},
+		{
+			name: "base after the links",
+			html: `<head>
+				<link rel="alternate" type="application/rss+xml" href="feed.xml">
+				<base href="https://example.com/blog/">
+			</head>`,
+			want: []string{"https://example.com/blog/feed.xml"},
+		},
+		{
+			name: "links after body ignored",
+			html: `<head><link rel="alternate" type="application/rss+xml" href="/feed"></head>
+			<body><link rel="alternate" type="application/rss+xml" href="/comments/feed"></body>`,
+			want: []string{"/feed"},
+		},
+		{
+			name: "sloppy html",
+			html: `<!DOCTYPE html><html><head><meta charset=utf-8><title>A &mdash; B</title>
+				<LINK REL="alternate" TYPE="application/rss+xml" HREF="/feed?a=1&amp;b=2">
+				<link rel="alternate" type="application/rss+xml" href=" ">`,
+			want: []string{"/feed?a=1&b=2"},
+		},
+		{
+			name: "unquoted values",
+			html: `<head><meta name=viewport content=width=device-width>
+				<link rel=alternate type=application/rss+xml href=/feed/>
+				<link rel=alternate type=application/atom+xml title="a=b" href=/atom?a=1&amp;b=2>
+				<link rel='alternate' type = application/feed+json href=https://example.com/feed.json>
+			</head>`,
+			want: []string{"/feed/", "/atom?a=1&b=2", "https://example.com/feed.json"},
+		},
+		{
+			name: "no links",
+			html: `<html><head><title>Example</title></head><body><p>Hi</p></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := feedLinks([]byte(tt.html)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: feedLinks() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post.html")
+
+	tests := []struct {
+		name  string
+		base  *url.URL
+		hrefs []string
+		want  []string
+	}{
+		{"no base", nil, []string{"feed.xml", "/atom"}, []string{"feed.xml", "/atom"}},
+		{"relative", base, []string{"feed.xml", "../atom", "?format=rss"}, []string{
+			"https://example.com/blog/feed.xml",
+			"https://example.com/atom",
+			"https://example.com/blog/post.html?format=rss",
+		}},
+		{"absolute", base, []string{"//cdn.example.org/feed", "http://example.net/rss"}, []string{
+			"https://cdn.example.org/feed",
+			"http://example.net/rss",
+		}},
+		{"unparsable kept", base, []string{"http://[::1"}, []string{"http://[::1"}},
+		{"none", base, nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := resolveAll(tt.base, slices.Clone(tt.hrefs)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: resolveAll(%q) = %q, want %q", tt.name, tt.hrefs, got, tt.want)
+		}
+	}
+}
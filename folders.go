@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahmadfudl/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// findFolder returns the id of the folder of user_id called name, an empty
+// name is no folder at all.
+func findFolder(ctx context.Context, s *state, user_id uuid.UUID, name string) (uuid.NullUUID, error) {
+	if name == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	folder, err := s.db.GetFolder(ctx,
+		database.GetFolderParams{
+			UserID: user_id,
+			Name:   name,
+		})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.NullUUID{}, fmt.Errorf(`fatal: Folder '%s' doesn't exist.
+
+Usage: gator folder add <name>`,
+				name)
+		}
+		return uuid.NullUUID{}, fmt.Errorf("gator: %w", err)
+	}
+
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
+
+// ensureFolder is findFolder creating the folder when it doesn't exist yet.
+func ensureFolder(ctx context.Context, s *state, user_id uuid.UUID, name string) (uuid.NullUUID, error) {
+	if name == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	folder, err := s.db.GetFolder(ctx,
+		database.GetFolderParams{
+			UserID: user_id,
+			Name:   name,
+		})
+	if errors.Is(err, sql.ErrNoRows) {
+		folder, err = s.db.CreateFolder(ctx,
+			database.CreateFolderParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				UserID:    user_id,
+			})
+	}
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: folder.ID, Valid: true}, nil
+}
@@ -5,15 +5,96 @@ import (
 	"encoding/json"
 	"os"
 	"path"
+	"time"
 )
 
 type Config struct {
-	Db_url            string `json:"db_url"`
-	Current_user_name string `json:"current_user_name"`
+	Db_url            string   `json:"db_url"`
+	Current_user_name string   `json:"current_user_name"`
+	Max_failures      int      `json:"max_failures,omitempty"`
+	Min_interval      Duration `json:"min_interval,omitempty"`
+	Max_interval      Duration `json:"max_interval,omitempty"`
+	Host_interval     Duration `json:"host_interval,omitempty"`
+	Host_concurrency  int      `json:"host_concurrency,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "30m" in the
+// config file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
 }
 
 const config_file_name = ".gatorconfig.json"
 
+// defaults for the optional settings
+const (
+	default_max_failures     = 10
+	default_min_interval     = 5 * time.Minute
+	default_max_interval     = 24 * time.Hour
+	default_host_interval    = time.Second
+	default_host_concurrency = 2
+)
+
+// MaxFailures returns the number of consecutive failed fetches after which
+// a feed gets disabled.
+func (c *Config) MaxFailures() int {
+	if c.Max_failures <= 0 {
+		return default_max_failures
+	}
+	return c.Max_failures
+}
+
+// MinInterval returns the shortest time the aggregator waits between two
+// fetches of a feed, however busy it is.
+func (c *Config) MinInterval() time.Duration {
+	if c.Min_interval <= 0 {
+		return default_min_interval
+	}
+	return time.Duration(c.Min_interval)
+}
+
+// MaxInterval returns the longest time the aggregator waits between two
+// fetches of a feed, however quiet it is.
+func (c *Config) MaxInterval() time.Duration {
+	if c.Max_interval <= 0 {
+		return max(default_max_interval, c.MinInterval())
+	}
+	return max(time.Duration(c.Max_interval), c.MinInterval())
+}
+
+// HostInterval returns the average time between two requests to the same
+// host.
+func (c *Config) HostInterval() time.Duration {
+	if c.Host_interval <= 0 {
+		return default_host_interval
+	}
+	return time.Duration(c.Host_interval)
+}
+
+// HostConcurrency returns the number of requests to the same host that can
+// be in flight at once.
+func (c *Config) HostConcurrency() int {
+	if c.Host_concurrency <= 0 {
+		return default_host_concurrency
+	}
+	return c.Host_concurrency
+}
+
 func Read() (*Config, error) {
 	path, err := get_config_file_path()
 	if err != nil {
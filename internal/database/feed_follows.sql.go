@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,14 +17,14 @@ const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH
 	new_feed_follow AS (
 		INSERT INTO
-			feed_follows (id, created_at, updated_at, feed_id, user_id)
+			feed_follows (id, created_at, updated_at, feed_id, user_id, folder_id)
 		VALUES
-			($1, $2, $3, $4, $5)
+			($1, $2, $3, $4, $5, $6)
 		RETURNING
-			id, created_at, updated_at, feed_id, user_id
+			id, created_at, updated_at, feed_id, user_id, folder_id
 	)
 SELECT
-	new_feed_follow.id, new_feed_follow.created_at, new_feed_follow.updated_at, new_feed_follow.feed_id, new_feed_follow.user_id,
+	new_feed_follow.id, new_feed_follow.created_at, new_feed_follow.updated_at, new_feed_follow.feed_id, new_feed_follow.user_id, new_feed_follow.folder_id,
 	users.name AS user,
 	feeds.name AS feed
 FROM
@@ -38,6 +39,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	FeedID    uuid.UUID
 	UserID    uuid.UUID
+	FolderID  uuid.NullUUID
 }
 
 type CreateFeedFollowRow struct {
@@ -46,6 +48,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	FeedID    uuid.UUID
 	UserID    uuid.UUID
+	FolderID  uuid.NullUUID
 	User      string
 	Feed      string
 }
@@ -57,6 +60,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.FeedID,
 		arg.UserID,
+		arg.FolderID,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -65,6 +69,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.FeedID,
 		&i.UserID,
+		&i.FolderID,
 		&i.User,
 		&i.Feed,
 	)
@@ -87,30 +92,61 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const deleteFeedFollowsForUser = `-- name: DeleteFeedFollowsForUser :exec
+DELETE FROM feed_follows
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFollowsForUser, userID)
+	return err
+}
+
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-	feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.feed_id, feed_follows.user_id,
-	feeds.name AS feed,
-	feeds.url  AS url
+	feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.feed_id, feed_follows.user_id, feed_follows.folder_id,
+	feeds.name     AS feed,
+	feeds.url      AS url,
+	feeds.site_url AS site_url,
+	folders.name   AS folder
 FROM
 	feed_follows
 	INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+	LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE
 	feed_follows.user_id = $1
+	AND (
+		$2::UUID IS NULL
+		OR feed_follows.folder_id = $2
+	)
+ORDER BY
+	folders.name ASC NULLS FIRST,
+	feeds.name
 `
 
+type GetFeedFollowsForUserParams struct {
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+}
+
 type GetFeedFollowsForUserRow struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	FeedID    uuid.UUID
 	UserID    uuid.UUID
+	FolderID  uuid.NullUUID
 	Feed      string
 	Url       string
+	SiteUrl   sql.NullString
+	Folder    sql.NullString
 }
 
-func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+// The follows of a user grouped by folder, the ones outside of any folder
+// first. Only the follows in folder_id when it's not null.
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, arg.UserID, arg.FolderID)
 	if err != nil {
 		return nil, err
 	}
@@ -124,8 +160,11 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.FeedID,
 			&i.UserID,
+			&i.FolderID,
 			&i.Feed,
 			&i.Url,
+			&i.SiteUrl,
+			&i.Folder,
 		); err != nil {
 			return nil, err
 		}
@@ -139,3 +178,62 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET
+	feed_id    = $1,
+	updated_at = $2
+WHERE
+	feed_follows.feed_id = $3
+	AND feed_follows.user_id NOT IN (
+		SELECT
+			followed.user_id
+		FROM
+			feed_follows AS followed
+		WHERE
+			followed.feed_id = $1
+	)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+// Moves the follows of one feed onto another, users who already follow both
+// keep the follow they have.
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET
+	folder_id  = $1,
+	updated_at = $2
+WHERE
+	user_id = $3 AND feed_id = $4
+`
+
+type SetFeedFollowFolderParams struct {
+	FolderID  uuid.NullUUID
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder,
+		arg.FolderID,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -13,13 +13,153 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimDueFeeds = `-- name: ClaimDueFeeds :many
+UPDATE feeds
+SET
+	lease_expires_at = $1::TIMESTAMP,
+	updated_at       = $2::TIMESTAMP
+WHERE
+	id IN (
+		SELECT
+			id
+		FROM
+			feeds
+		WHERE
+			disabled_at IS NULL
+			AND (
+				lease_expires_at IS NULL
+				OR lease_expires_at <= $2::TIMESTAMP
+			)
+			AND (
+				next_fetch_at IS NULL
+				OR next_fetch_at <= $2::TIMESTAMP
+			)
+		ORDER BY
+			next_fetch_at ASC NULLS FIRST
+		LIMIT
+			$3
+		FOR UPDATE SKIP LOCKED
+	)
+RETURNING
+	id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, failure_count, disabled_at, lease_expires_at, min_interval, skip_hours, skip_days, next_fetch_at, moved_from, moved_at, site_url, url_key
+`
+
+type ClaimDueFeedsParams struct {
+	LeaseUntil time.Time
+	Now        time.Time
+	MaxFeeds   int32
+}
+
+// Claims up to max_feeds enabled feeds whose next_fetch_at has come, in the
+// order they became due. Claimed feeds are leased until lease_until, rows
+// locked or leased by another aggregator are skipped, and a lease that
+// expired without being released (a crashed aggregator) is claimable again.
+func (q *Queries) ClaimDueFeeds(ctx context.Context, arg ClaimDueFeedsParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueFeeds, arg.LeaseUntil, arg.Now, arg.MaxFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.FailureCount,
+			&i.DisabledAt,
+			&i.LeaseExpiresAt,
+			&i.MinInterval,
+			&i.SkipHours,
+			&i.SkipDays,
+			&i.NextFetchAt,
+			&i.MovedFrom,
+			&i.MovedAt,
+			&i.SiteUrl,
+			&i.UrlKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimFeed = `-- name: ClaimFeed :one
+UPDATE feeds
+SET
+	lease_expires_at = $1::TIMESTAMP,
+	updated_at       = $2::TIMESTAMP
+WHERE
+	url_key = $3
+	AND (
+		lease_expires_at IS NULL
+		OR lease_expires_at <= $2::TIMESTAMP
+	)
+RETURNING
+	id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, failure_count, disabled_at, lease_expires_at, min_interval, skip_hours, skip_days, next_fetch_at, moved_from, moved_at, site_url, url_key
+`
+
+type ClaimFeedParams struct {
+	LeaseUntil time.Time
+	Now        time.Time
+	UrlKey     string
+}
+
+// Claims the feed with url_key whatever its schedule, unless another
+// aggregator holds its lease.
+func (q *Queries) ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimFeed, arg.LeaseUntil, arg.Now, arg.UrlKey)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.NextFetchAt,
+		&i.MovedFrom,
+		&i.MovedAt,
+		&i.SiteUrl,
+		&i.UrlKey,
+	)
+	return i, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO
-	feeds (id, created_at, updated_at, name, url, user_id)
+	feeds (id, created_at, updated_at, name, url, url_key, user_id)
 VAlUES
-	($1, $2, $3, $4, $5, $6)
+	($1, $2, $3, $4, $5, $6, $7)
 RETURNING
-	id, created_at, updated_at, name, url, user_id, last_fetched_at
+	id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, failure_count, disabled_at, lease_expires_at, min_interval, skip_hours, skip_days, next_fetch_at, moved_from, moved_at, site_url, url_key
 `
 
 type CreateFeedParams struct {
@@ -28,6 +168,7 @@ type CreateFeedParams struct {
 	UpdatedAt time.Time
 	Name      string
 	Url       string
+	UrlKey    string
 	UserID    uuid.UUID
 }
 
@@ -38,6 +179,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.UpdatedAt,
 		arg.Name,
 		arg.Url,
+		arg.UrlKey,
 		arg.UserID,
 	)
 	var i Feed
@@ -49,21 +191,125 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.NextFetchAt,
+		&i.MovedFrom,
+		&i.MovedAt,
+		&i.SiteUrl,
+		&i.UrlKey,
 	)
 	return i, err
 }
 
+const deferFeed = `-- name: DeferFeed :exec
+UPDATE feeds
+SET
+	next_fetch_at    = $1,
+	lease_expires_at = NULL,
+	updated_at       = $2
+WHERE
+	id = $3
+`
+
+type DeferFeedParams struct {
+	NextFetchAt sql.NullTime
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+// Releases the lease on a feed that wasn't fetched, it's due again at
+// next_fetch_at.
+func (q *Queries) DeferFeed(ctx context.Context, arg DeferFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeed, arg.NextFetchAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE
+	id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const deleteFeedsForUser = `-- name: DeleteFeedsForUser :exec
+DELETE FROM feeds
+WHERE
+	user_id = $1
+`
+
+func (q *Queries) DeleteFeedsForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedsForUser, userID)
+	return err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET
+	disabled_at = $1,
+	updated_at  = $2
+WHERE
+	id = $3
+`
+
+type DisableFeedParams struct {
+	DisabledAt sql.NullTime
+	UpdatedAt  time.Time
+	ID         uuid.UUID
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.DisabledAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :execrows
+UPDATE feeds
+SET
+	disabled_at   = NULL,
+	next_fetch_at = NULL,
+	failure_count = 0,
+	updated_at    = $1
+WHERE
+	url_key = $2
+`
+
+type EnableFeedParams struct {
+	UpdatedAt time.Time
+	UrlKey    string
+}
+
+func (q *Queries) EnableFeed(ctx context.Context, arg EnableFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableFeed, arg.UpdatedAt, arg.UrlKey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getFeed = `-- name: GetFeed :one
 SELECT
-	id, created_at, updated_at, name, url, user_id, last_fetched_at
+	id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, failure_count, disabled_at, lease_expires_at, min_interval, skip_hours, skip_days, next_fetch_at, moved_from, moved_at, site_url, url_key
 FROM
 	feeds
 WHERE
-	url = $1
+	url_key = $1
 `
 
-func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getFeed, url)
+func (q *Queries) GetFeed(ctx context.Context, urlKey string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, urlKey)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -73,24 +319,88 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.MinInterval,
+		&i.SkipHours,
+		&i.SkipDays,
+		&i.NextFetchAt,
+		&i.MovedFrom,
+		&i.MovedAt,
+		&i.SiteUrl,
+		&i.UrlKey,
 	)
 	return i, err
 }
 
+const getFeedStats = `-- name: GetFeedStats :one
+SELECT
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feed_follows
+		WHERE
+			feed_follows.feed_id = $1
+	) AS followers,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			posts
+		WHERE
+			posts.feed_id = $1
+	) AS posts
+`
+
+type GetFeedStatsRow struct {
+	Followers int64
+	Posts     int64
+}
+
+// What deleting a feed takes with it.
+func (q *Queries) GetFeedStats(ctx context.Context, id uuid.UUID) (GetFeedStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedStats, id)
+	var i GetFeedStatsRow
+	err := row.Scan(&i.Followers, &i.Posts)
+	return i, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
 SELECT
-	feeds.name AS name,
-	feeds.url  AS url,
-	users.name AS creator
+	feeds.name          AS name,
+	feeds.url           AS url,
+	users.name          AS creator,
+	feeds.failure_count AS failure_count,
+	feeds.last_error    AS last_error,
+	feeds.last_error_at AS last_error_at,
+	feeds.disabled_at   AS disabled_at,
+	feeds.next_fetch_at AS next_fetch_at,
+	feeds.moved_from    AS moved_from,
+	feeds.moved_at      AS moved_at,
+	feeds.site_url      AS site_url
 FROM
 	feeds
 	JOIN users ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
-	Name    string
-	Url     string
-	Creator string
+	Name         string
+	Url          string
+	Creator      string
+	FailureCount int32
+	LastError    sql.NullString
+	LastErrorAt  sql.NullTime
+	DisabledAt   sql.NullTime
+	NextFetchAt  sql.NullTime
+	MovedFrom    sql.NullString
+	MovedAt      sql.NullTime
+	SiteUrl      sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -102,7 +412,19 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.Creator); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.Creator,
+			&i.FailureCount,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.DisabledAt,
+			&i.NextFetchAt,
+			&i.MovedFrom,
+			&i.MovedAt,
+			&i.SiteUrl,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -116,48 +438,249 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeed = `-- name: GetNextFeed :one
-SELECT
-	id, created_at, updated_at, name, url, user_id, last_fetched_at
-FROM
-	feeds
-ORDER BY
-	last_fetched_at ASC NULLS FIRST
-LIMIT
-	1
+const markFeedFailed = `-- name: MarkFeedFailed :one
+UPDATE feeds
+SET
+	last_fetched_at  = $1,
+	next_fetch_at    = $2,
+	last_error       = $3,
+	last_error_at    = $1,
+	failure_count    = failure_count + 1,
+	lease_expires_at = NULL,
+	updated_at       = $4
+WHERE
+	id = $5
+RETURNING
+	failure_count
 `
 
-func (q *Queries) GetNextFeed(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeed)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
+type MarkFeedFailedParams struct {
+	LastFetchedAt sql.NullTime
+	NextFetchAt   sql.NullTime
+	LastError     sql.NullString
+	UpdatedAt     time.Time
+	ID            uuid.UUID
+}
+
+// Releases the lease on a feed whose fetch failed.
+func (q *Queries) MarkFeedFailed(ctx context.Context, arg MarkFeedFailedParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, markFeedFailed,
+		arg.LastFetchedAt,
+		arg.NextFetchAt,
+		arg.LastError,
+		arg.UpdatedAt,
+		arg.ID,
 	)
-	return i, err
+	var failure_count int32
+	err := row.Scan(&failure_count)
+	return failure_count, err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET
-	last_fetched_at = $1,
-	updated_at      = $2
+	last_fetched_at  = $1,
+	next_fetch_at    = $2,
+	failure_count    = 0,
+	lease_expires_at = NULL,
+	updated_at       = $3
 WHERE
-	id = $3
+	id = $4
 `
 
 type MarkFeedFetchedParams struct {
 	LastFetchedAt sql.NullTime
+	NextFetchAt   sql.NullTime
 	UpdatedAt     time.Time
 	ID            uuid.UUID
 }
 
+// Releases the lease on a successfully fetched feed.
 func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		arg.LastFetchedAt,
+		arg.NextFetchAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const moveFeed = `-- name: MoveFeed :exec
+UPDATE feeds
+SET
+	url        = $1,
+	url_key    = $2,
+	moved_from = $3,
+	moved_at   = $4,
+	updated_at = $5
+WHERE
+	id = $6
+`
+
+type MoveFeedParams struct {
+	Url       string
+	UrlKey    string
+	MovedFrom sql.NullString
+	MovedAt   sql.NullTime
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) MoveFeed(ctx context.Context, arg MoveFeedParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeed,
+		arg.Url,
+		arg.UrlKey,
+		arg.MovedFrom,
+		arg.MovedAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const releaseFeed = `-- name: ReleaseFeed :exec
+UPDATE feeds
+SET
+	lease_expires_at = NULL
+WHERE
+	id = $1
+`
+
+func (q *Queries) ReleaseFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeed, id)
+	return err
+}
+
+const renameFeed = `-- name: RenameFeed :exec
+UPDATE feeds
+SET
+	name       = $1,
+	updated_at = $2
+WHERE
+	id = $3
+`
+
+type RenameFeedParams struct {
+	Name      string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) error {
+	_, err := q.db.ExecContext(ctx, renameFeed, arg.Name, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedCacheHeaders = `-- name: SetFeedCacheHeaders :exec
+UPDATE feeds
+SET
+	etag          = $1,
+	last_modified = $2,
+	updated_at    = $3
+WHERE
+	id = $4
+`
+
+type SetFeedCacheHeadersParams struct {
+	Etag         sql.NullString
+	LastModified sql.NullString
+	UpdatedAt    time.Time
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetFeedCacheHeaders(ctx context.Context, arg SetFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheHeaders,
+		arg.Etag,
+		arg.LastModified,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const setFeedSchedule = `-- name: SetFeedSchedule :exec
+UPDATE feeds
+SET
+	min_interval = $1,
+	skip_hours   = $2,
+	skip_days    = $3,
+	updated_at   = $4
+WHERE
+	id = $5
+`
+
+type SetFeedScheduleParams struct {
+	MinInterval int32
+	SkipHours   int32
+	SkipDays    int32
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) SetFeedSchedule(ctx context.Context, arg SetFeedScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSchedule,
+		arg.MinInterval,
+		arg.SkipHours,
+		arg.SkipDays,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const setFeedSiteUrl = `-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET
+	site_url   = $1,
+	updated_at = $2
+WHERE
+	id = $3
+`
+
+type SetFeedSiteUrlParams struct {
+	SiteUrl   sql.NullString
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) SetFeedSiteUrl(ctx context.Context, arg SetFeedSiteUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteUrl, arg.SiteUrl, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :exec
+UPDATE feeds
+SET
+	url           = $1,
+	url_key       = $2,
+	etag          = NULL,
+	last_modified = NULL,
+	last_error    = NULL,
+	last_error_at = NULL,
+	failure_count = 0,
+	disabled_at   = NULL,
+	next_fetch_at = NULL,
+	updated_at    = $3
+WHERE
+	id = $4
+`
+
+type SetFeedUrlParams struct {
+	Url       string
+	UrlKey    string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Points a feed at a new url, what gator learnt fetching the old one is
+// forgotten and the feed is due right away.
+func (q *Queries) SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedUrl,
+		arg.Url,
+		arg.UrlKey,
+		arg.UpdatedAt,
+		arg.ID,
+	)
 	return err
 }
@@ -12,13 +12,28 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Name           string
+	Url            string
+	UserID         uuid.UUID
+	LastFetchedAt  sql.NullTime
+	Etag           sql.NullString
+	LastModified   sql.NullString
+	LastError      sql.NullString
+	LastErrorAt    sql.NullTime
+	FailureCount   int32
+	DisabledAt     sql.NullTime
+	LeaseExpiresAt sql.NullTime
+	MinInterval    int32
+	SkipHours      int32
+	SkipDays       int32
+	NextFetchAt    sql.NullTime
+	MovedFrom      sql.NullString
+	MovedAt        sql.NullTime
+	SiteUrl        sql.NullString
+	UrlKey         string
 }
 
 type FeedFollow struct {
@@ -27,17 +42,28 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	FeedID    uuid.UUID
 	UserID    uuid.UUID
+	FolderID  uuid.NullUUID
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	UserID    uuid.UUID
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          sql.NullString
+	PublishedAt          sql.NullTime
+	FeedID               uuid.UUID
+	PublishedAtEstimated bool
+	Guid                 string
 }
 
 type User struct {
@@ -13,50 +13,93 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :exec
-INSERT INTO
-	posts (
-		id,
-		created_at,
-		updated_at,
-		title,
-		url,
-		description,
-		published_at,
-		feed_id
+const adoptPost = `-- name: AdoptPost :exec
+UPDATE posts
+SET
+	guid       = $1,
+	updated_at = $2
+WHERE
+	posts.feed_id = $3
+	AND posts.guid = $4
+	AND posts.url = $4
+	AND NOT EXISTS (
+		SELECT
+			1
+		FROM
+			posts AS adopted
+		WHERE
+			adopted.feed_id = $3
+			AND adopted.guid = $1
 	)
-VALUES
-	($1, $2, $3, $4, $5, $6, $7, $8)
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+type AdoptPostParams struct {
+	Guid      string
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Url       string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
+// Gives the post stored under its link, as every post was before posts had a
+// guid, the guid it's known by now.
+func (q *Queries) AdoptPost(ctx context.Context, arg AdoptPostParams) error {
+	_, err := q.db.ExecContext(ctx, adoptPost,
+		arg.Guid,
 		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
 		arg.FeedID,
+		arg.Url,
 	)
 	return err
 }
 
+const getPostDates = `-- name: GetPostDates :many
+SELECT
+	published_at
+FROM
+	posts
+WHERE
+	feed_id = $1
+	AND published_at IS NOT NULL
+	AND NOT published_at_estimated
+ORDER BY
+	published_at DESC
+LIMIT
+	$2
+`
+
+type GetPostDatesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+// The most recent publication dates of a feed's posts, estimated ones are
+// left out since they're only the time gator first saw the post.
+func (q *Queries) GetPostDates(ctx context.Context, arg GetPostDatesParams) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getPostDates, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullTime
+	for rows.Next() {
+		var published_at sql.NullTime
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsUser = `-- name: GetPostsUser :many
 SELECT
-	id, created_at, updated_at, title, url, description, published_at, feed_id
+	id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_estimated, guid
 FROM
 	posts
 WHERE
@@ -67,18 +110,25 @@ WHERE
 			feed_follows
 		WHERE
 			user_id = $1
+			AND (
+				$2::UUID IS NULL
+				OR folder_id = $2
+			)
 	)
+ORDER BY published_at DESC
 LIMIT
-	$2
+	$3
 `
 
 type GetPostsUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	FolderID uuid.NullUUID
+	Limit    int32
 }
 
+// Only the posts of the feeds in folder_id when it's not null.
 func (q *Queries) GetPostsUser(ctx context.Context, arg GetPostsUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsUser, arg.UserID, arg.FolderID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +145,8 @@ func (q *Queries) GetPostsUser(ctx context.Context, arg GetPostsUserParams) ([]P
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.PublishedAtEstimated,
+			&i.Guid,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +160,104 @@ func (q *Queries) GetPostsUser(ctx context.Context, arg GetPostsUserParams) ([]P
 	}
 	return items, nil
 }
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET
+	feed_id    = $1,
+	updated_at = $2
+WHERE
+	posts.feed_id = $3
+	AND posts.guid NOT IN (
+		SELECT
+			kept.guid
+		FROM
+			posts AS kept
+		WHERE
+			kept.feed_id = $1
+	)
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+// Moves the posts of one feed onto another, posts the other feed already has
+// are left behind.
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
+
+const upsertPost = `-- name: UpsertPost :one
+INSERT INTO
+	posts (
+		id,
+		created_at,
+		updated_at,
+		title,
+		url,
+		description,
+		published_at,
+		published_at_estimated,
+		guid,
+		feed_id
+	)
+VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET
+	updated_at             = EXCLUDED.updated_at,
+	title                  = EXCLUDED.title,
+	url                    = EXCLUDED.url,
+	description            = EXCLUDED.description,
+	published_at           = CASE
+		WHEN EXCLUDED.published_at_estimated THEN posts.published_at
+		ELSE EXCLUDED.published_at
+	END,
+	published_at_estimated = posts.published_at_estimated
+		AND EXCLUDED.published_at_estimated
+WHERE
+	posts.title IS DISTINCT FROM EXCLUDED.title
+	OR posts.url IS DISTINCT FROM EXCLUDED.url
+	OR posts.description IS DISTINCT FROM EXCLUDED.description
+	OR (
+		NOT EXCLUDED.published_at_estimated
+		AND posts.published_at IS DISTINCT FROM EXCLUDED.published_at
+	)
+RETURNING
+	(xmax = 0)::BOOLEAN AS inserted
+`
+
+type UpsertPostParams struct {
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Title                string
+	Url                  string
+	Description          sql.NullString
+	PublishedAt          sql.NullTime
+	PublishedAtEstimated bool
+	Guid                 string
+	FeedID               uuid.UUID
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.PublishedAtEstimated,
+		arg.Guid,
+		arg.FeedID,
+	)
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
+}
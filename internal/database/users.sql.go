@@ -45,6 +45,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE
+	id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users
 `
@@ -75,6 +86,65 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 	return i, err
 }
 
+const getUserStats = `-- name: GetUserStats :one
+SELECT
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feeds
+		WHERE
+			feeds.user_id = $1
+	) AS feeds,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feed_follows
+		WHERE
+			feed_follows.user_id = $1
+	) AS follows,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			folders
+		WHERE
+			folders.user_id = $1
+	) AS folders,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feed_follows
+			INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+		WHERE
+			feeds.user_id = $1
+			AND feed_follows.user_id <> $1
+	) AS other_follows
+`
+
+type GetUserStatsRow struct {
+	Feeds        int64
+	Follows      int64
+	Folders      int64
+	OtherFollows int64
+}
+
+// What deleting a user takes with it, the feeds they added go too along with
+// the follows other users have of them.
+func (q *Queries) GetUserStats(ctx context.Context, id uuid.UUID) (GetUserStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStats, id)
+	var i GetUserStatsRow
+	err := row.Scan(
+		&i.Feeds,
+		&i.Follows,
+		&i.Folders,
+		&i.OtherFollows,
+	)
+	return i, err
+}
+
 const getUsers = `-- name: GetUsers :many
 SELECT
 	id, created_at, updated_at, name
@@ -109,3 +179,26 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const renameUser = `-- name: RenameUser :execrows
+UPDATE users
+SET
+	name       = $1,
+	updated_at = $2
+WHERE
+	name = $3
+`
+
+type RenameUserParams struct {
+	NewName   string
+	UpdatedAt time.Time
+	Name      string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameUser, arg.NewName, arg.UpdatedAt, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
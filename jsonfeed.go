@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strings"
+)
+
+// jsonFeedVersion prefixes the version of every JSON Feed document, it tells
+// feeds apart from any other JSON.
+const jsonFeedVersion = "https://jsonfeed.org/version/"
+
+type (
+	// jsonFeed is a JSON Feed 1.0/1.1 document, see https://jsonfeed.org.
+	jsonFeed struct {
+		Version     string         `json:"version"`
+		Title       string         `json:"title"`
+		HomePageURL string         `json:"home_page_url"`
+		Description string         `json:"description"`
+		Items       []jsonFeedItem `json:"items"`
+	}
+	jsonFeedItem struct {
+		// the spec requires a string id but some publishers emit numbers
+		ID            json.RawMessage `json:"id"`
+		URL           string          `json:"url"`
+		Title         string          `json:"title"`
+		ContentHTML   string          `json:"content_html"`
+		ContentText   string          `json:"content_text"`
+		Summary       string          `json:"summary"`
+		DatePublished string          `json:"date_published"`
+		DateModified  string          `json:"date_modified"`
+	}
+)
+
+func isJSON(data []byte, contentType string) bool {
+	mediatype, _, _ := mime.ParseMediaType(contentType)
+	if mediatype == "application/feed+json" || mediatype == "application/json" {
+		return true
+	}
+	data = bytes.TrimLeft(data, " \t\r\n\ufeff")
+	return len(data) > 0 && data[0] == '{'
+}
+
+func (jf *jsonFeed) to_feed() *Feed {
+	feed := &Feed{
+		Channel: Channel{
+			Title:       jf.Title,
+			Link:        Link{Href: jf.HomePageURL},
+			Description: jf.Description,
+		},
+	}
+
+	for _, it := range jf.Items {
+		item := Item{
+			Title:       it.Title,
+			Link:        it.URL,
+			Description: it.Summary,
+			PubDate:     it.DatePublished,
+			Guid:        json_string(it.ID),
+		}
+		if item.Description == "" {
+			item.Description = it.ContentHTML
+		}
+		if item.Description == "" {
+			item.Description = it.ContentText
+		}
+		if item.PubDate == "" {
+			item.PubDate = it.DateModified
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	return feed
+}
+
+// json_string returns a string or number JSON value as a string.
+func json_string(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
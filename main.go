@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"embed"
 	"fmt"
 	"io/fs"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/ahmadfudl/gator/internal/config"
 	"github.com/ahmadfudl/gator/internal/database"
@@ -18,6 +21,7 @@ import (
 type state struct {
 	cfg  *config.Config
 	db   *database.Queries
+	conn *sql.DB
 	prov *goose.Provider
 }
 
@@ -76,14 +80,17 @@ func main() {
 
 	fsys := os.DirFS(tempdir)
 
-	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys, goose.WithSessionLocker(psqlock))
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys,
+		goose.WithSessionLocker(psqlock), goose.WithGoMigrations(goMigrations...))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "gator: %v\n", err)
 		os.Exit(1)
 	}
 
+	hosts = newHostLimiter(cfg.HostInterval(), cfg.HostConcurrency())
+
 	dbqs := database.New(db)
-	s := state{cfg: cfg, db: dbqs, prov: provider}
+	s := state{cfg: cfg, db: dbqs, conn: db, prov: provider}
 
 	c := commands{
 		make(map[string]handler),
@@ -101,17 +108,25 @@ func main() {
 		f: _register,
 	})
 	c.register("reset", handler{
-		d: "reset all database records",
+		d: "reset a user's or all database records",
 		f: _reset,
 	})
 	c.register("users", handler{
 		d: "list users",
 		f: _users,
 	})
+	c.register("user", handler{
+		d: "manage users",
+		f: _user,
+	})
 	c.register("agg", handler{
-		d: "fetch rss feed",
+		d: "fetch rss feeds",
 		f: _agg,
 	})
+	c.register("fetch", handler{
+		d: "fetch a single feed now",
+		f: _fetch,
+	})
 	c.register("addfeed", handler{
 		d: "add new feed",
 		f: _addfeed,
@@ -120,6 +135,18 @@ func main() {
 		d: "list feeds",
 		f: _feeds,
 	})
+	c.register("feed", handler{
+		d: "manage a feed",
+		f: _feed,
+	})
+	c.register("import", handler{
+		d: "import feeds from a file",
+		f: _import,
+	})
+	c.register("export", handler{
+		d: "export feeds to a file",
+		f: _export,
+	})
 	c.register("follow", handler{
 		d: "follow feed",
 		f: _follow,
@@ -132,6 +159,14 @@ func main() {
 		d: "list followed feeds",
 		f: _following,
 	})
+	c.register("folders", handler{
+		d: "list your folders",
+		f: _folders,
+	})
+	c.register("folder", handler{
+		d: "manage your folders",
+		f: _folder,
+	})
 	c.register("browse", handler{
 		d: "view all posts from the feeds the user follows",
 		f: _browse,
@@ -151,7 +186,14 @@ func main() {
 	cmd.name = os.Args[1]
 	cmd.args = os.Args[2:]
 
-	err = c.run(&s, cmd)
+	// cancelled on the first SIGINT/SIGTERM, a second one kills gator
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = c.run(ctx, &s, cmd)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
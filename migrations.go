@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+)
+
+// goMigrations are the migrations that can't be written in SQL, they share
+// their version numbers with the ones in sql/schema.
+var goMigrations = []*goose.Migration{
+	// merged feeds can't be told apart again, going down is a no-op
+	goose.NewGoMigration(15, &goose.GoFunc{RunTx: mergeFeedURLs}, nil),
+	// 19 adds the column and 21 drops it, which is all there is to undo
+	goose.NewGoMigration(20, &goose.GoFunc{RunTx: setFeedURLKeys}, nil),
+}
+
+type feedRow struct {
+	id  uuid.UUID
+	url string
+	key string
+}
+
+// feedRows returns every feed with its url key, oldest first. Urls gator
+// can't fetch anyway are their own key.
+func feedRows(ctx context.Context, tx *sql.Tx) ([]feedRow, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, url FROM feeds ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var feeds []feedRow
+	for rows.Next() {
+		var f feedRow
+		if err := rows.Scan(&f.id, &f.url); err != nil {
+			return nil, err
+		}
+		if _, key, err := feedURL(f.url); err == nil {
+			f.key = key
+		} else {
+			f.key = strings.TrimSpace(f.url)
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// mergeFeedURLs merges the feeds whose urls turn out to be the same feed
+// into the one added first, the urls themselves are left as they are.
+func mergeFeedURLs(ctx context.Context, tx *sql.Tx) error {
+	feeds, err := feedRows(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	kept := make(map[string]feedRow)
+	for _, f := range feeds {
+		to, ok := kept[f.key]
+		if !ok {
+			kept[f.key] = f
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE feed_follows
+			SET feed_id = $1, updated_at = $2
+			WHERE feed_id = $3 AND user_id NOT IN (
+				SELECT user_id FROM feed_follows WHERE feed_id = $1
+			)`, to.id, now, f.id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE posts
+			SET feed_id = $1, updated_at = $2
+			WHERE feed_id = $3 AND guid NOT IN (
+				SELECT guid FROM posts WHERE feed_id = $1
+			)`, to.id, now, f.id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM feeds WHERE id = $1`, f.id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("gator: merged feed %s into %s\n", f.url, to.url)
+	}
+
+	return nil
+}
+
+// setFeedURLKeys fills in the url_key of every feed, merging the feeds
+// added since migration 15 that would end up with the same one.
+func setFeedURLKeys(ctx context.Context, tx *sql.Tx) error {
+	if err := mergeFeedURLs(ctx, tx); err != nil {
+		return err
+	}
+
+	feeds, err := feedRows(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, f := range feeds {
+		_, err = tx.ExecContext(ctx, `UPDATE feeds SET url_key = $1 WHERE id = $2`, f.key, f.id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
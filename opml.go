@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ahmadfudl/gator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type (
+	opmlDoc struct {
+		XMLName     xml.Name      `xml:"opml"`
+		Version     string        `xml:"version,attr"`
+		Title       string        `xml:"head>title"`
+		DateCreated string        `xml:"head>dateCreated,omitempty"`
+		Outlines    []opmlOutline `xml:"body>outline"`
+	}
+	opmlOutline struct {
+		Text     string        `xml:"text,attr"`
+		Title    string        `xml:"title,attr,omitempty"`
+		Type     string        `xml:"type,attr,omitempty"`
+		XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+		HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+		Outlines []opmlOutline `xml:"outline"`
+	}
+)
+
+// opmlEntry is a subscription of an OPML file, category is the path of the
+// outline folders it's nested in, separated by slashes.
+type opmlEntry struct {
+	name     string
+	url      string
+	siteURL  string
+	category string
+}
+
+func (o *opmlOutline) name() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// parseOPML returns the subscriptions of an OPML document in the order they
+// appear in it.
+func parseOPML(data []byte) ([]opmlEntry, error) {
+	doc := &opmlDoc{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("opml: %w", err)
+	}
+
+	var entries []opmlEntry
+	var walk func(outlines []opmlOutline, category string)
+	walk = func(outlines []opmlOutline, category string) {
+		for i := range outlines {
+			o := &outlines[i]
+			if o.XMLURL != "" {
+				entries = append(entries, opmlEntry{
+					name:     o.name(),
+					url:      strings.TrimSpace(o.XMLURL),
+					siteURL:  strings.TrimSpace(o.HTMLURL),
+					category: category,
+				})
+				continue
+			}
+
+			// an outline without a feed is a folder
+			folder := o.name()
+			if category != "" && folder != "" {
+				folder = category + "/" + folder
+			} else if folder == "" {
+				folder = category
+			}
+			walk(o.Outlines, folder)
+		}
+	}
+	walk(doc.Outlines, "")
+
+	return entries, nil
+}
+
+// writeOPML writes entries to w as an OPML 2.0 document, their categories
+// become nested outline folders.
+func writeOPML(w io.Writer, title string, entries []opmlEntry) error {
+	doc := opmlDoc{
+		Version:     "2.0",
+		Title:       title,
+		DateCreated: time.Now().Format(time.RFC1123Z),
+		Outlines:    opmlOutlines(entries),
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "\t")
+	if err != nil {
+		return fmt.Errorf("opml: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// opmlOutlines nests entries in folders after their categories, folders come
+// in the order their first entry does.
+func opmlOutlines(entries []opmlEntry) []opmlOutline {
+	var outlines []opmlOutline
+	folders := make(map[string]int)
+	nested := make(map[string][]opmlEntry)
+	for _, e := range entries {
+		if e.category == "" {
+			outlines = append(outlines, opmlOutline{
+				Text:    e.name,
+				Title:   e.name,
+				Type:    "rss",
+				XMLURL:  e.url,
+				HTMLURL: e.siteURL,
+			})
+			continue
+		}
+
+		folder, rest, _ := strings.Cut(e.category, "/")
+		if _, ok := folders[folder]; !ok {
+			folders[folder] = len(outlines)
+			outlines = append(outlines, opmlOutline{Text: folder, Title: folder})
+		}
+		e.category = rest
+		nested[folder] = append(nested[folder], e)
+	}
+
+	for folder, i := range folders {
+		outlines[i].Outlines = opmlOutlines(nested[folder])
+	}
+
+	return outlines
+}
+
+// importSummary tallies what became of the entries of an imported file.
+type importSummary struct {
+	imported int
+	skipped  int
+	invalid  int
+}
+
+func (i importSummary) String() string {
+	return fmt.Sprintf("%d imported, %d already followed, %d invalid",
+		i.imported, i.skipped, i.invalid)
+}
+
+// importFeeds follows every entry for user in the folder named after its
+// category, adding the folders and feeds gator doesn't know yet. Feeds aren't
+// fetched, agg picks the new ones up on its next poll.
+func importFeeds(ctx context.Context, s *state, user database.User, entries []opmlEntry) importSummary {
+	summary := importSummary{}
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		url, key, err := feedURL(e.url)
+		if err != nil {
+			summary.invalid++
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			continue
+		}
+		name := e.name
+		if name == "" {
+			name = url
+		}
+
+		feed, err := s.db.GetFeed(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			feed, err = s.db.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				Url:       url,
+				UrlKey:    key,
+				UserID:    user.ID,
+			})
+		}
+		if err != nil {
+			summary.invalid++
+			fmt.Fprintf(os.Stderr, "gator: %s: %v\n", url, err)
+			continue
+		}
+
+		folder_id, err := ensureFolder(ctx, s, user.ID, e.category)
+		if err != nil {
+			summary.invalid++
+			fmt.Fprintf(os.Stderr, "gator: %s: %v\n", url, err)
+			continue
+		}
+
+		_, err = s.db.CreateFeedFollow(ctx,
+			database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				FeedID:    feed.ID,
+				UserID:    user.ID,
+				FolderID:  folder_id,
+			})
+		if err != nil {
+			// 23505 unique_violation, the feed is already followed
+			if err, ok := err.(*pq.Error); ok && err.Code == "23505" {
+				summary.skipped++
+				continue
+			}
+			summary.invalid++
+			fmt.Fprintf(os.Stderr, "gator: %s: %v\n", url, err)
+			continue
+		}
+
+		summary.imported++
+		fmt.Printf("* %s (%s)\n", feed.Name, feed.Url)
+	}
+
+	return summary
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestParseOPML(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []opmlEntry
+	}{
+		{
+			name: "flat",
+			data: `<?xml version="1.0"?>
+<opml version="1.0">
+	<head><title>Subscriptions</title></head>
+	<body>
+		<outline text="Example" type="rss" xmlUrl="https://example.com/feed" htmlUrl="https://example.com/"/>
+		<outline text="Text" title=" Title " xmlUrl=" https://example.org/feed "/>
+	</body>
+</opml>`,
+			want: []opmlEntry{
+				{name: "Example", url: "https://example.com/feed", siteURL: "https://example.com/"},
+				{name: "Title", url: "https://example.org/feed"},
+			},
+		},
+		{
+			name: "folders",
+			data: `<opml version="2.0">
+	<body>
+		<outline text="Tech">
+			<outline text="Go" xmlUrl="https://go.dev/blog/feed.atom"/>
+			<outline title="Databases" text="DB">
+				<outline text="Postgres" xmlUrl="https://postgresql.org/news.rss"/>
+			</outline>
+		</outline>
+		<outline text="">
+			<outline text="Unnamed" xmlUrl="https://example.com/feed"/>
+			<outline>
+				<outline text="Deeper" xmlUrl="https://example.org/feed"/>
+			</outline>
+		</outline>
+		<outline text="Tech">
+			<outline text="Rust" xmlUrl="https://blog.rust-lang.org/feed.xml"/>
+		</outline>
+		<outline text="Empty folder"/>
+	</body>
+</opml>`,
+			want: []opmlEntry{
+				{name: "Go", url: "https://go.dev/blog/feed.atom", category: "Tech"},
+				{name: "Postgres", url: "https://postgresql.org/news.rss", category: "Tech/Databases"},
+				{name: "Unnamed", url: "https://example.com/feed"},
+				{name: "Deeper", url: "https://example.org/feed"},
+				{name: "Rust", url: "https://blog.rust-lang.org/feed.xml", category: "Tech"},
+			},
+		},
+		{
+			name: "no subscriptions",
+			data: `<opml version="2.0"><head><title>Empty</title></head><body/></opml>`,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOPML([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: parseOPML() =\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseOPMLInvalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`<opml><body><outline text="Example"></body></opml>`,
+		`<rss><channel><title>Example</title></channel></rss>`,
+	} {
+		if _, err := parseOPML([]byte(data)); err == nil {
+			t.Errorf("parseOPML(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestOPMLOutlines(t *testing.T) {
+	entries := []opmlEntry{
+		{name: "Go", url: "https://go.dev/blog/feed.atom", category: "Tech"},
+		{name: "Example", url: "https://example.com/feed", siteURL: "https://example.com/"},
+		{name: "Postgres", url: "https://postgresql.org/news.rss", category: "Tech/Databases"},
+		{name: "Rust", url: "https://blog.rust-lang.org/feed.xml", category: "Tech"},
+		{name: "Cooking", url: "https://example.org/feed", category: "Home"},
+	}
+
+	got := opmlOutlines(entries)
+
+	want := []string{"Tech", "Example", "Home"}
+	var names []string
+	for _, o := range got {
+		names = append(names, o.Text)
+	}
+	if !slices.Equal(names, want) {
+		t.Fatalf("top level outlines = %q, want %q", names, want)
+	}
+
+	tech := got[0]
+	if tech.XMLURL != "" || len(tech.Outlines) != 3 {
+		t.Fatalf("Tech folder = %+v, want a folder of 3 outlines", tech)
+	}
+	want = []string{"Go", "Databases", "Rust"}
+	names = nil
+	for _, o := range tech.Outlines {
+		names = append(names, o.Text)
+	}
+	if !slices.Equal(names, want) {
+		t.Errorf("Tech outlines = %q, want %q", names, want)
+	}
+	if db := tech.Outlines[1]; len(db.Outlines) != 1 || db.Outlines[0].XMLURL != "https://postgresql.org/news.rss" {
+		t.Errorf("Databases folder = %+v, want the Postgres feed", db)
+	}
+
+	feed := got[1]
+	if feed.Type != "rss" || feed.XMLURL != "https://example.com/feed" || feed.HTMLURL != "https://example.com/" {
+		t.Errorf("Example outline = %+v", feed)
+	}
+}
+
+func TestWriteOPML(t *testing.T) {
+	entries := []opmlEntry{
+		{name: "Go", url: "https://go.dev/blog/feed.atom", category: "Tech"},
+		{name: "A & B", url: "https://example.com/feed?a=1&b=2", siteURL: "https://example.com/"},
+		{name: "Postgres", url: "https://postgresql.org/news.rss", category: "Tech/Databases"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeOPML(&buf, "Subscriptions", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// the folders come out in their own order, only the categories matter
+	got, err := parseOPML(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parseOPML of\n%s\n%v", buf.String(), err)
+	}
+	want := []opmlEntry{entries[0], entries[2], entries[1]}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseOPML(writeOPML()) =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hosts paces the requests fetchFeed makes, it's shared by every fetch so
+// concurrent workers don't all hit the same host at once. main replaces it
+// with one configured from the config file.
+var hosts = newHostLimiter(time.Second, 2)
+
+// hostLimiter rate limits requests per host with a token bucket refilled
+// with one token every interval, holding up to concurrency tokens, and caps
+// the number of requests in flight to a host at concurrency.
+type hostLimiter struct {
+	interval    time.Duration
+	concurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*hostBucket
+}
+
+type hostBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+	// set when the host answered with a Retry-After header
+	blocked time.Time
+}
+
+// hostBlockedError is returned instead of making a request to a host that
+// asked, through a Retry-After header, not to be contacted until then.
+type hostBlockedError struct {
+	host  string
+	until time.Time
+}
+
+func (e *hostBlockedError) Error() string {
+	return fmt.Sprintf("rss: %s asked to wait until %s", e.host, e.until.Format(time.TimeOnly))
+}
+
+func newHostLimiter(interval time.Duration, concurrency int) *hostLimiter {
+	return &hostLimiter{
+		interval:    interval,
+		concurrency: max(concurrency, 1),
+		hosts:       make(map[string]*hostBucket),
+	}
+}
+
+func (l *hostLimiter) bucket(host string) *hostBucket {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &hostBucket{
+			tokens: float64(l.concurrency),
+			last:   time.Now(),
+			slots:  make(chan struct{}, l.concurrency),
+		}
+		l.hosts[host] = b
+	}
+	return b
+}
+
+// wait blocks until a request to host is allowed or ctx is done, on success
+// the returned func must be called once the request is over. While the host
+// is blocked by a Retry-After, wait doesn't block but fails with a
+// *hostBlockedError.
+func (l *hostLimiter) wait(ctx context.Context, host string) (func(), error) {
+	b := l.bucket(host)
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-b.slots }
+
+	for {
+		d, until := b.reserve(l.interval, l.concurrency)
+		if !until.IsZero() {
+			release()
+			return nil, &hostBlockedError{host: host, until: until}
+		}
+		if d <= 0 {
+			return release, nil
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			release()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token if there's one, otherwise it returns how long to
+// wait before trying again. While the host is blocked it returns until when.
+func (b *hostBucket) reserve(interval time.Duration, burst int) (wait time.Duration, until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.blocked) {
+		return 0, b.blocked
+	}
+
+	if interval > 0 {
+		b.tokens += float64(now.Sub(b.last)) / float64(interval)
+	} else {
+		b.tokens = float64(burst)
+	}
+	b.tokens = min(b.tokens, float64(burst))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, time.Time{}
+	}
+	return time.Duration((1 - b.tokens) * float64(interval)), time.Time{}
+}
+
+// block holds off every request to host until t.
+func (l *hostLimiter) block(host string, t time.Time) {
+	b := l.bucket(host)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t.After(b.blocked) {
+		b.blocked = t
+	}
+}
+
+// retryAfter parses a Retry-After header, either a number of seconds or an
+// HTTP date, into a duration from now.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	t, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
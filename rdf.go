@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+)
+
+const rdfNS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+type (
+	// rdfFeed is an RSS 1.0 document, unlike RSS 2.0 the items are siblings
+	// of the channel rather than its children.
+	rdfFeed struct {
+		Channel rdfChannel `xml:"channel"`
+		Items   []rdfItem  `xml:"item"`
+	}
+	rdfChannel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	}
+	rdfItem struct {
+		About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	}
+)
+
+func (rf *rdfFeed) to_feed() *Feed {
+	feed := &Feed{
+		Channel: Channel{
+			Title:           strings.TrimSpace(rf.Channel.Title),
+			Link:            Link{Href: strings.TrimSpace(rf.Channel.Link)},
+			Description:     strings.TrimSpace(rf.Channel.Description),
+			UpdatePeriod:    rf.Channel.UpdatePeriod,
+			UpdateFrequency: rf.Channel.UpdateFrequency,
+		},
+	}
+
+	for _, it := range rf.Items {
+		feed.Channel.Items = append(feed.Channel.Items, Item{
+			Title:       strings.TrimSpace(it.Title),
+			Link:        strings.TrimSpace(it.Link),
+			Description: strings.TrimSpace(it.Description),
+			PubDate:     strings.TrimSpace(it.Date),
+			Guid:        strings.TrimSpace(it.About),
+		})
+	}
+
+	return feed
+}
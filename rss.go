@@ -1,18 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ahmadfudl/gator/internal/database"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
 type (
@@ -20,46 +26,274 @@ type (
 		Channel Channel `xml:"channel"`
 	}
 	Channel struct {
-		Title       string `xml:"title"`
-		Link        Link   `xml:"link"`
-		Description string `xml:"description"`
-		Items       []Item `xml:"item"`
+		Title       string    `xml:"title"`
+		Link        Link      `xml:"-"`
+		Links       []rssLink `xml:"link"`
+		Description string    `xml:"description"`
+		Items       []Item    `xml:"item"`
+		// publisher hints on how often to fetch the feed
+		TTL             string   `xml:"ttl"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 	}
 	Link struct {
 		Href string `xml:"href,attr"`
 	}
+	// the website of an RSS channel is the text of its <link>, the
+	// atom:link elements that share its name carry an href instead
+	rssLink struct {
+		XMLName xml.Name
+		Href    string `xml:"href,attr"`
+		Text    string `xml:",chardata"`
+	}
 	Item struct {
 		Title       string `xml:"title"`
 		Link        string `xml:"link"`
 		Description string `xml:"description"`
 		PubDate     string `xml:"pubDate"`
+		Guid        string `xml:"guid"`
 	}
 )
 
-func fetchFeed(ctx context.Context, url string) (*Feed, error) {
+// cacheHeaders are the validators of the last successful fetch of a feed,
+// they're sent back on the next fetch so an unchanged feed can answer with
+// 304 Not Modified instead of its whole body.
+type cacheHeaders struct {
+	etag         string
+	lastModified string
+}
+
+// errNotModified is returned by fetchFeed when the feed hasn't changed since
+// the fetch cache came from, it's a successful fetch with nothing to parse.
+var errNotModified = errors.New("rss: feed not modified")
+
+// Failed fetches wrap one of these, so callers can tell with errors.Is
+// whether a feed is gone for good or just having a bad day.
+var (
+	errNotFound         = errors.New("feed not found")
+	errGone             = errors.New("feed gone")
+	errRateLimited      = errors.New("rate limited")
+	errServerError      = errors.New("server error")
+	errUnexpectedStatus = errors.New("unexpected status")
+	errNotAFeed         = errors.New("not a feed")
+)
+
+// statusError is a fetch that failed because of the response's status code.
+type statusError struct {
+	code int
+	err  error
+	// how long the server asked to wait before trying again, if it did
+	retry time.Duration
+}
+
+func (e *statusError) Error() string {
+	if e.retry > 0 {
+		return fmt.Sprintf("rss: %v (%d %s, retry after %v)",
+			e.err, e.code, http.StatusText(e.code), e.retry.Round(time.Second))
+	}
+	return fmt.Sprintf("rss: %v (%d %s)", e.err, e.code, http.StatusText(e.code))
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+// notFeedError is a fetch that got a document that isn't a feed, it keeps
+// the document since an HTML page may still link to the feed.
+type notFeedError struct {
+	url         string
+	contentType string
+	body        []byte
+	err         error
+}
+
+func (e *notFeedError) Error() string {
+	return fmt.Sprintf("rss: %v: %v", errNotAFeed, e.err)
+}
+
+func (e *notFeedError) Unwrap() error {
+	return errNotAFeed
+}
+
+func checkStatus(code int) error {
+	switch {
+	case code >= 200 && code < 300:
+		return nil
+	case code == http.StatusNotFound:
+		return &statusError{code: code, err: errNotFound}
+	case code == http.StatusGone:
+		return &statusError{code: code, err: errGone}
+	case code == http.StatusTooManyRequests:
+		return &statusError{code: code, err: errRateLimited}
+	case code >= 500:
+		return &statusError{code: code, err: errServerError}
+	}
+	return &statusError{code: code, err: errUnexpectedStatus}
+}
+
+// fetchFeed fetches and parses the feed at url. When cache is not nil the
+// request is made conditional on it and, on success, cache is updated with
+// the response's validators.
+// moved is the feed's new url when it was only reached through permanent
+// redirects, temporary ones are followed without being reported.
+func fetchFeed(ctx context.Context, url string, cache *cacheHeaders) (feed *Feed, moved string, err error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("rss: %w", err)
+		return nil, "", fmt.Errorf("rss: %w", err)
 	}
 	req.Header.Set("user-agent", "gator")
+	if cache != nil {
+		if cache.etag != "" {
+			req.Header.Set("if-none-match", cache.etag)
+		}
+		if cache.lastModified != "" {
+			req.Header.Set("if-modified-since", cache.lastModified)
+		}
+	}
+
+	release, err := hosts.wait(ctx, req.URL.Host)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
 
+	permanent := true
 	c := &http.Client{
 		Timeout: 5 * time.Minute,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			// once a temporary redirect is in the chain, the rest of it
+			// says nothing about where the feed itself lives
+			switch req.Response.StatusCode {
+			case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+				if permanent {
+					moved = req.URL.String()
+				}
+			default:
+				permanent = false
+			}
+			return nil
+		},
 	}
 	res, err := c.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("rss: %w", err)
+		return nil, "", fmt.Errorf("rss: %w", err)
 	}
 	defer res.Body.Close()
 
-	feed := &Feed{}
-	dec := xml.NewDecoder(res.Body)
-	if err := dec.Decode(feed); err != nil {
-		return nil, fmt.Errorf("rss: %w", err)
+	if res.StatusCode == http.StatusNotModified {
+		return nil, moved, errNotModified
+	}
+	if err := checkStatus(res.StatusCode); err != nil {
+		// the whole host is told to slow down, not just this feed
+		if res.StatusCode == http.StatusTooManyRequests ||
+			res.StatusCode == http.StatusServiceUnavailable {
+			if d, ok := retryAfter(res.Header.Get("retry-after"), time.Now()); ok {
+				err.(*statusError).retry = d
+				hosts.block(req.URL.Host, time.Now().Add(d))
+			}
+		}
+		return nil, "", err
+	}
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("rss: %w", err)
+	}
+
+	feed, err = parseFeed(data, res.Header.Get("content-type"))
+	if err != nil {
+		return nil, "", &notFeedError{
+			url:         res.Request.URL.String(),
+			contentType: res.Header.Get("content-type"),
+			body:        data,
+			err:         err,
+		}
 	}
 	feed.html_unescape_feed()
 
-	return feed, nil
+	if cache != nil {
+		cache.etag = res.Header.Get("etag")
+		cache.lastModified = res.Header.Get("last-modified")
+	}
+
+	return feed, moved, nil
+}
+
+// parseFeed decodes an RSS 2.0, RSS 1.0 (RDF), Atom 1.0 or JSON Feed
+// document into a Feed.
+// JSON is detected from the content type or the first byte of the body and
+// must carry a JSON Feed version, the XML formats are told apart by the
+// document's root element.
+func parseFeed(data []byte, contentType string) (*Feed, error) {
+	if isJSON(data, contentType) {
+		jf := &jsonFeed{}
+		data = bytes.TrimPrefix(data, []byte("\ufeff"))
+		if err := json.Unmarshal(data, jf); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(jf.Version, jsonFeedVersion) {
+			return nil, fmt.Errorf("unsupported feed format, JSON without a JSON Feed version")
+		}
+		return jf.to_feed(), nil
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Local {
+	case "rss":
+		feed := &Feed{}
+		if err := xml.Unmarshal(data, feed); err != nil {
+			return nil, err
+		}
+		for _, l := range feed.Channel.Links {
+			if l.XMLName.Space == "" && strings.TrimSpace(l.Text) != "" {
+				feed.Channel.Link.Href = strings.TrimSpace(l.Text)
+				break
+			}
+		}
+		return feed, nil
+	case "feed":
+		if root.Space != atomNS {
+			break
+		}
+		af := &atomFeed{}
+		if err := xml.Unmarshal(data, af); err != nil {
+			return nil, err
+		}
+		return af.to_feed(), nil
+	case "RDF":
+		if root.Space != rdfNS {
+			break
+		}
+		rf := &rdfFeed{}
+		if err := xml.Unmarshal(data, rf); err != nil {
+			return nil, err
+		}
+		return rf.to_feed(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported feed format <%s>", root.Local)
+}
+
+func rootElement(data []byte) (xml.Name, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name, nil
+		}
+	}
 }
 
 func (f *Feed) html_unescape_feed() {
@@ -71,66 +305,375 @@ func (f *Feed) html_unescape_feed() {
 	}
 }
 
-func scrapeFeeds(s *state) {
-	f, err := s.db.GetNextFeed(context.Background())
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "gator: %v", err)
-		return
+// identity returns the value a post is known by within its feed: the
+// item's guid (Atom id), falling back to its link and, for items that have
+// neither, a hash of its content.
+func (it *Item) identity() string {
+	if guid := strings.TrimSpace(it.Guid); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(it.Link); link != "" {
+		return link
+	}
+	sum := sha1.Sum([]byte(it.Title + "\x00" + it.Description))
+	return "sha1:" + hex.EncodeToString(sum[:])
+}
+
+// scrapeFeed fetches the claimed feed f, saves its posts and releases it
+// with its next fetch scheduled. When ctx is cancelled mid-fetch f is only
+// released, it's due again right away.
+func scrapeFeed(ctx context.Context, s *state, f database.Feed) scrapeResult {
+	r := scrapeResult{url: f.Url}
+	// f is released even once ctx is cancelled, or it would stay claimed
+	// until its lease expires
+	db_ctx := context.WithoutCancel(ctx)
+
+	cache := cacheHeaders{
+		etag:         f.Etag.String,
+		lastModified: f.LastModified.String,
+	}
+	feed, moved, err := fetchFeed(ctx, f.Url, &cache)
+	// the host asked to wait before another feed of it was fetched, f itself
+	// wasn't even requested
+	var blocked *hostBlockedError
+	if errors.As(err, &blocked) {
+		fmt.Fprintf(os.Stderr, "gator: %s: %v\n", f.Url, err)
+		r.deferred = blocked.until
+		deferFeed(db_ctx, s, f, blocked.until)
+		return r
+	}
+	if err != nil && !errors.Is(err, errNotModified) {
+		r.err = err
+		// an aborted fetch isn't the feed's failure
+		if ctx.Err() != nil {
+			releaseFeeds(db_ctx, s, []database.Feed{f})
+		} else {
+			markFeedFailed(db_ctx, s, f, err)
+		}
+		return r
 	}
 
-	err = s.db.MarkFeedFetched(context.Background(),
+	// moved is empty unless the feed was only reached through permanent
+	// redirects
+	if moved != "" && moved != f.Url {
+		to, err := moveFeed(ctx, s, f, moved)
+		if errors.Is(err, errFeedLeased) {
+			// nothing is saved this time, f is merged on a later fetch
+			r.deferred = time.Now()
+			if to.LeaseExpiresAt.Time.After(r.deferred) {
+				r.deferred = to.LeaseExpiresAt.Time
+			}
+			fmt.Fprintf(os.Stderr, "gator: %s: moved permanently to %s, which is being fetched\n",
+				f.Url, to.Url)
+			deferFeed(db_ctx, s, f, r.deferred)
+			return r
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "gator: %s: moved permanently to %s\n", f.Url, to.Url)
+			f = to
+			r.url = f.Url
+		}
+	}
+
+	if feed == nil {
+		r.notModified = true
+		markFeedFetched(db_ctx, s, f)
+		return r
+	}
+	r.title, r.link = feed.Channel.Title, feed.Channel.Link.Href
+	r.added, r.updated = saveFeed(ctx, s, f, feed, cache)
+	// the posts left unsaved are picked up by the next fetch
+	if ctx.Err() != nil {
+		r.err = ctx.Err()
+	}
+
+	return r
+}
+
+// saveFeed stores the freshly fetched feed of f along with the cache headers
+// it came with, and releases f with its next fetch scheduled. When ctx is
+// cancelled before every post is saved f is released as is, so the next
+// fetch gets the whole feed again rather than a 304.
+func saveFeed(ctx context.Context, s *state, f database.Feed, feed *Feed, cache cacheHeaders) (added, updated int) {
+	added, updated = savePosts(ctx, s, f.ID, feed)
+	db_ctx := context.WithoutCancel(ctx)
+	if ctx.Err() != nil {
+		releaseFeeds(db_ctx, s, []database.Feed{f})
+		return added, updated
+	}
+
+	if cache.etag != f.Etag.String || cache.lastModified != f.LastModified.String {
+		err := s.db.SetFeedCacheHeaders(db_ctx,
+			database.SetFeedCacheHeadersParams{
+				Etag:         sql.NullString{String: cache.etag, Valid: cache.etag != ""},
+				LastModified: sql.NullString{String: cache.lastModified, Valid: cache.lastModified != ""},
+				UpdatedAt:    time.Now(),
+				ID:           f.ID,
+			})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		}
+	}
+
+	if site_url := strings.TrimSpace(feed.Channel.Link.Href); site_url != f.SiteUrl.String {
+		err := s.db.SetFeedSiteUrl(db_ctx,
+			database.SetFeedSiteUrlParams{
+				SiteUrl:   sql.NullString{String: site_url, Valid: site_url != ""},
+				UpdatedAt: time.Now(),
+				ID:        f.ID,
+			})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		}
+	}
+
+	min_interval := int32(feed.Channel.minInterval() / time.Second)
+	skip_hours, skip_days := feed.Channel.skipMasks()
+	if min_interval != f.MinInterval || skip_hours != f.SkipHours || skip_days != f.SkipDays {
+		err := s.db.SetFeedSchedule(db_ctx,
+			database.SetFeedScheduleParams{
+				MinInterval: min_interval,
+				SkipHours:   skip_hours,
+				SkipDays:    skip_days,
+				UpdatedAt:   time.Now(),
+				ID:          f.ID,
+			})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		}
+		f.MinInterval, f.SkipHours, f.SkipDays = min_interval, skip_hours, skip_days
+	}
+
+	markFeedFetched(db_ctx, s, f)
+
+	return added, updated
+}
+
+// markFeedFetched records a successful fetch of f and schedules the next
+// one from the feed's posting cadence.
+func markFeedFetched(ctx context.Context, s *state, f database.Feed) {
+	now := time.Now()
+	err := s.db.MarkFeedFetched(ctx,
 		database.MarkFeedFetchedParams{
-			LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+			NextFetchAt:   sql.NullTime{Time: nextFetch(ctx, s, f, now), Valid: true},
+			UpdatedAt:     now,
+			ID:            f.ID,
+		})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+	}
+}
+
+// deferFeed releases f without fetching it, it's due again at t.
+func deferFeed(ctx context.Context, s *state, f database.Feed, t time.Time) {
+	err := s.db.DeferFeed(ctx,
+		database.DeferFeedParams{
+			NextFetchAt: sql.NullTime{Time: t, Valid: true},
+			UpdatedAt:   time.Now(),
+			ID:          f.ID,
+		})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+	}
+}
+
+// markFeedFailed records a failed fetch of f and backs off from it,
+// disabling the feed once it has failed too many times in a row or when it's
+// gone for good.
+func markFeedFailed(ctx context.Context, s *state, f database.Feed, fetch_err error) {
+	now := time.Now()
+	wait := backoff(f.FailureCount + 1)
+	var se *statusError
+	if errors.As(fetch_err, &se) {
+		wait = max(wait, se.retry)
+	}
+
+	failures, err := s.db.MarkFeedFailed(ctx,
+		database.MarkFeedFailedParams{
+			LastFetchedAt: sql.NullTime{Time: now, Valid: true},
+			NextFetchAt:   sql.NullTime{Time: now.Add(wait), Valid: true},
+			LastError:     sql.NullString{String: fetch_err.Error(), Valid: true},
 			UpdatedAt:     time.Now(),
 			ID:            f.ID,
 		})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "gator: %v", err)
+		fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+		return
+	}
+
+	if !errors.Is(fetch_err, errGone) && int(failures) < s.cfg.MaxFailures() {
 		return
 	}
 
-	feed, err := fetchFeed(context.Background(), f.Url)
+	err = s.db.DisableFeed(ctx,
+		database.DisableFeedParams{
+			DisabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+			UpdatedAt:  time.Now(),
+			ID:         f.ID,
+		})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "gator: %v", err)
+		fmt.Fprintf(os.Stderr, "gator: %v\n", err)
 		return
 	}
+	fmt.Fprintf(os.Stderr, "gator: %s: disabled after %d failures, see gator feed enable\n",
+		f.Url, failures)
+}
+
+// errFeedLeased is returned by moveFeed when the feed f moved to is being
+// fetched by another aggregator, the two can't be merged yet.
+var errFeedLeased = errors.New("gator: feed is being fetched by another aggregator")
+
+// moveFeed points f at url, where it has permanently moved to, and returns
+// the updated feed. When another feed already has url, it's claimed, f's
+// follows and posts are merged into it and f is deleted, the other feed is
+// returned then. If the other feed can't be claimed it's returned along with
+// errFeedLeased.
+func moveFeed(ctx context.Context, s *state, f database.Feed, url string) (database.Feed, error) {
+	url, key, err := feedURL(url)
+	if err != nil {
+		return f, err
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return f, err
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
 
+	now := time.Now()
+	// the feed at url may be f itself, moving to https or adding a slash
+	to, err := q.GetFeed(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && to.ID == f.ID) {
+		to = f
+	} else if err != nil {
+		return f, err
+	} else {
+		// the caller goes on saving the feed into to, it has to hold its
+		// lease like it held f's
+		leased := to
+		to, err = q.ClaimFeed(ctx,
+			database.ClaimFeedParams{
+				LeaseUntil: now.Add(lease_duration),
+				Now:        now,
+				UrlKey:     key,
+			})
+		if errors.Is(err, sql.ErrNoRows) {
+			return leased, errFeedLeased
+		} else if err != nil {
+			return f, err
+		}
+
+		err = q.MoveFeedFollows(ctx,
+			database.MoveFeedFollowsParams{
+				ToFeedID:   to.ID,
+				UpdatedAt:  now,
+				FromFeedID: f.ID,
+			})
+		if err != nil {
+			return f, err
+		}
+		err = q.MovePosts(ctx,
+			database.MovePostsParams{
+				ToFeedID:   to.ID,
+				UpdatedAt:  now,
+				FromFeedID: f.ID,
+			})
+		if err != nil {
+			return f, err
+		}
+		// follows and posts the other feed already had go with f
+		err = q.DeleteFeed(ctx, f.ID)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	to.Url, to.UrlKey = url, key
+	to.MovedFrom = sql.NullString{String: f.Url, Valid: true}
+	to.MovedAt = sql.NullTime{Time: now, Valid: true}
+	err = q.MoveFeed(ctx,
+		database.MoveFeedParams{
+			Url:       to.Url,
+			UrlKey:    to.UrlKey,
+			MovedFrom: to.MovedFrom,
+			MovedAt:   to.MovedAt,
+			UpdatedAt: now,
+			ID:        to.ID,
+		})
+	if err != nil {
+		return f, err
+	}
+
+	return to, tx.Commit()
+}
+
+// savePosts upserts every item of feed into the posts of feed_id, items that
+// are already stored are only touched when their content changed.
+func savePosts(ctx context.Context, s *state, feed_id uuid.UUID, feed *Feed) (added, updated int) {
+	fetched_at := time.Now()
 	items := feed.Channel.Items
 	for i := range items {
-		cp := database.CreatePostParams{
+		if ctx.Err() != nil {
+			break
+		}
+
+		up := database.UpsertPostParams{
 			ID:          uuid.New(),
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 			Title:       items[i].Title,
 			Url:         items[i].Link,
 			Description: sql.NullString{String: items[i].Description},
-			FeedID:      f.ID,
+			Guid:        items[i].identity(),
+			FeedID:      feed_id,
 		}
 		if items[i].Description != "" {
-			cp.Description.Valid = true
+			up.Description.Valid = true
 		}
 
-		layout := "Mon, 02 Jan 2006 15:04:05 -0700"
-		pubdata, err := time.Parse(layout, items[i].PubDate)
+		// fall back to the fetch time so the post still sorts sensibly
+		pubdate, err := parseDate(items[i].PubDate)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "gator %v", err)
+			up.PublishedAt = sql.NullTime{Time: fetched_at, Valid: true}
+			up.PublishedAtEstimated = true
 		} else {
-			cp.PublishedAt = sql.NullTime{Time: pubdata, Valid: true}
+			up.PublishedAt = sql.NullTime{Time: pubdate, Valid: true}
 		}
 
-		err = s.db.CreatePost(context.Background(), cp)
+		// posts stored before they had a guid are known by their link
+		if up.Url != "" && up.Guid != up.Url {
+			err = s.db.AdoptPost(ctx,
+				database.AdoptPostParams{
+					Guid:      up.Guid,
+					UpdatedAt: time.Now(),
+					FeedID:    feed_id,
+					Url:       up.Url,
+				})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+				continue
+			}
+		}
+
+		inserted, err := s.db.UpsertPost(ctx, up)
 		if err != nil {
-			// error code for unique constraint vioaltion
-			// 23505 unique_violation
-			// https://www.postgresql.org/docs/9.3/errcodes-appendix.html
-			if err, ok := err.(*pq.Error); ok && err.Code.Class() == "23505" {
-				return
+			// no row is returned when the stored post is unchanged
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
 			}
-			fmt.Fprintf(os.Stderr, "gator %v", err)
+			fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+			continue
+		}
+		if inserted {
+			added++
+		} else {
+			updated++
 		}
 	}
 
-	fmt.Printf("channel:\n\ttitle: %s\n\tlink: %s\n",
-		feed.Channel.Title, feed.Channel.Link.Href)
-
+	return added, updated
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		title       string
+		link        string
+		description string
+		items       []Item
+	}{
+		{
+			name: "rss",
+			data: `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Example</title>
+	<atom:link href="https://example.com/feed.xml" rel="self"/>
+	<link>https://example.com/</link>
+	<description>An example feed</description>
+	<item>
+		<title>First</title>
+		<link>https://example.com/1</link>
+		<description>&lt;p&gt;One&lt;/p&gt;</description>
+		<pubDate>Wed, 01 May 2024 10:00:00 GMT</pubDate>
+		<guid isPermaLink="false">1</guid>
+	</item>
+	<item>
+		<title>Second</title>
+		<link>https://example.com/2</link>
+	</item>
+</channel>
+</rss>`,
+			title:       "Example",
+			link:        "https://example.com/",
+			description: "An example feed",
+			items: []Item{
+				{
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "<p>One</p>",
+					PubDate:     "Wed, 01 May 2024 10:00:00 GMT",
+					Guid:        "1",
+				},
+				{Title: "Second", Link: "https://example.com/2"},
+			},
+		},
+		{
+			name: "atom",
+			data: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title type="html">Example &amp;amp; co</title>
+	<subtitle>An example feed</subtitle>
+	<link href="https://example.com/feed.atom" rel="self"/>
+	<link href="https://example.com/"/>
+	<entry>
+		<id>tag:example.com,2024:1</id>
+		<title type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">First <em>post</em></div></title>
+		<link rel="alternate" href="https://example.com/1"/>
+		<summary>One</summary>
+		<content type="html">&lt;p&gt;One, in full&lt;/p&gt;</content>
+		<published>2024-05-01T10:00:00Z</published>
+		<updated>2024-05-02T10:00:00Z</updated>
+	</entry>
+	<entry>
+		<id>tag:example.com,2024:2</id>
+		<title>Second</title>
+		<link rel="edit" href="https://example.com/edit/2"/>
+		<link href="https://example.com/2"/>
+		<content type="html">&lt;p&gt;Two&lt;/p&gt;</content>
+		<updated>2024-05-02T10:00:00Z</updated>
+	</entry>
+</feed>`,
+			title:       "Example &amp; co",
+			link:        "https://example.com/",
+			description: "An example feed",
+			items: []Item{
+				{
+					Title:       "First <em>post</em>",
+					Link:        "https://example.com/1",
+					Description: "One",
+					PubDate:     "2024-05-01T10:00:00Z",
+					Guid:        "tag:example.com,2024:1",
+				},
+				{
+					Title:       "Second",
+					Link:        "https://example.com/2",
+					Description: "<p>Two</p>",
+					PubDate:     "2024-05-02T10:00:00Z",
+					Guid:        "tag:example.com,2024:2",
+				},
+			},
+		},
+		{
+			name: "rdf",
+			data: `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="https://example.com/">
+		<title> Example </title>
+		<link>https://example.com/</link>
+		<description>An example feed</description>
+	</channel>
+	<item rdf:about="https://example.com/1">
+		<title>First</title>
+		<link>https://example.com/1</link>
+		<description>One</description>
+		<dc:date>2024-05-01T10:00:00Z</dc:date>
+	</item>
+</rdf:RDF>`,
+			title:       "Example",
+			link:        "https://example.com/",
+			description: "An example feed",
+			items: []Item{
+				{
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "One",
+					PubDate:     "2024-05-01T10:00:00Z",
+					Guid:        "https://example.com/1",
+				},
+			},
+		},
+		{
+			name:        "json feed",
+			contentType: "application/feed+json; charset=utf-8",
+			data: `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example",
+	"home_page_url": "https://example.com/",
+	"description": "An example feed",
+	"items": [
+		{
+			"id": "1",
+			"url": "https://example.com/1",
+			"title": "First",
+			"summary": "One",
+			"content_html": "<p>One, in full</p>",
+			"date_published": "2024-05-01T10:00:00Z"
+		},
+		{
+			"id": 2,
+			"url": "https://example.com/2",
+			"content_html": "<p>Two</p>",
+			"date_modified": "2024-05-02T10:00:00Z"
+		},
+		{
+			"id": "3",
+			"content_text": "Three"
+		}
+	]
+}`,
+			title:       "Example",
+			link:        "https://example.com/",
+			description: "An example feed",
+			items: []Item{
+				{
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "One",
+					PubDate:     "2024-05-01T10:00:00Z",
+					Guid:        "1",
+				},
+				{
+					Link:        "https://example.com/2",
+					Description: "<p>Two</p>",
+					PubDate:     "2024-05-02T10:00:00Z",
+					Guid:        "2",
+				},
+				{Description: "Three", Guid: "3"},
+			},
+		},
+		{
+			name:        "json feed served as text",
+			contentType: "text/plain",
+			data:        "\ufeff\n{\"version\": \"https://jsonfeed.org/version/1\", \"title\": \"Example\", \"items\": [{\"id\": \"1\", \"title\": \"First\"}]}",
+			title:       "Example",
+			items:       []Item{{Title: "First", Guid: "1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		feed, err := parseFeed([]byte(tt.data), tt.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		c := feed.Channel
+		if c.Title != tt.title || c.Link.Href != tt.link || c.Description != tt.description {
+			t.Errorf("%s: channel = %q, %q, %q, want %q, %q, %q", tt.name,
+				c.Title, c.Link.Href, c.Description, tt.title, tt.link, tt.description)
+		}
+		if !slices.Equal(c.Items, tt.items) {
+			t.Errorf("%s: items =\n%+v\nwant\n%+v", tt.name, c.Items, tt.items)
+		}
+	}
+}
+
+func TestParseFeedInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+	}{
+		{"html", `<html><head><title>Example</title></head></html>`, "text/html"},
+		{"rdf without its namespace", `<RDF><channel><title>Example</title></channel></RDF>`, ""},
+		{"feed without the atom namespace", `<feed><title>Example</title><entry><title>First</title></entry></feed>`, ""},
+		{"feed in another namespace", `<feed xmlns="http://purl.org/atom/ns#"><title>Example</title></feed>`, ""},
+		{"broken xml", `<rss><channel><title>Example</channel>`, ""},
+		{"broken json", `{"title": "Example",`, ""},
+		{"json api", `{"status": "ok"}`, "application/json"},
+		{"empty json object", `{}`, ""},
+		{"json feed without a version", `{"title": "Example", "items": [{"id": "1"}]}`, "application/feed+json"},
+		{"json with another version", `{"version": "1.1", "title": "Example"}`, ""},
+		{"empty", ``, ""},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseFeed([]byte(tt.data), tt.contentType); err == nil {
+			t.Errorf("%s: parseFeed succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestItemIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		item Item
+		want string
+	}{
+		{"guid", Item{Guid: " 42 ", Link: "https://example.com/1"}, "42"},
+		{"link", Item{Link: " https://example.com/1 "}, "https://example.com/1"},
+		{"content", Item{Title: "a", Description: "b"}, "sha1:4a3dec2d1f8245280855c42db0ee4239f917fdb8"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.item.identity(); got != tt.want {
+			t.Errorf("%s: identity() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// the title and description are hashed apart
+	a := Item{Title: "ab", Description: "c"}
+	b := Item{Title: "a", Description: "bc"}
+	if a.identity() == b.identity() {
+		t.Errorf("identity() of %+v and %+v are both %q", a, b, a.identity())
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadfudl/gator/internal/database"
+)
+
+// default_interval is used for feeds with too few dated posts to learn
+// their cadence from.
+const default_interval = time.Hour
+
+// cadence_posts is how many of a feed's latest posts its cadence is
+// learned from.
+const cadence_posts = 10
+
+// max_min_interval caps the interval publishers can ask for, a feed is
+// fetched at least once a day whatever its hints say.
+const max_min_interval = 24 * time.Hour
+
+// minInterval returns the shortest time between fetches the publisher asks
+// for, through the RSS ttl or the Syndication module update period.
+func (c *Channel) minInterval() time.Duration {
+	var d time.Duration
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(c.TTL)); err == nil && ttl > 0 {
+		d = time.Duration(ttl) * time.Minute
+	}
+
+	var period time.Duration
+	switch strings.ToLower(strings.TrimSpace(c.UpdatePeriod)) {
+	case "hourly":
+		period = time.Hour
+	case "daily":
+		period = 24 * time.Hour
+	case "weekly":
+		period = 7 * 24 * time.Hour
+	case "monthly":
+		period = 30 * 24 * time.Hour
+	case "yearly":
+		period = 365 * 24 * time.Hour
+	}
+	if period > 0 {
+		// the period is split in updateFrequency updates, one by default
+		freq, err := strconv.Atoi(strings.TrimSpace(c.UpdateFrequency))
+		if err != nil || freq < 1 {
+			freq = 1
+		}
+		d = max(d, period/time.Duration(freq))
+	}
+
+	return min(d, max_min_interval)
+}
+
+// skipMasks returns the channel's skipHours and skipDays as bit sets, bit n
+// of hours is the GMT hour n and bit n of days is time.Weekday(n).
+func (c *Channel) skipMasks() (hours, days int32) {
+	for _, h := range c.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(h))
+		// some publishers count hours from 1 to 24
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		hours |= 1 << (hour % 24)
+	}
+
+	for _, d := range c.SkipDays {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(strings.TrimSpace(d), wd.String()) {
+				days |= 1 << wd
+			}
+		}
+	}
+
+	// skipping every hour or day would never fetch the feed again
+	if hours == 1<<24-1 {
+		hours = 0
+	}
+	if days == 1<<7-1 {
+		days = 0
+	}
+
+	return hours, days
+}
+
+// skipBits returns the bits of t's GMT hour and weekday in the masks
+// returned by skipMasks.
+func skipBits(t time.Time) (hour, day int32) {
+	t = t.UTC()
+	return 1 << t.Hour(), 1 << t.Weekday()
+}
+
+// nextFetch returns when f, successfully fetched at now, is due again. The
+// interval is the feed's posting cadence bounded by the configured min and
+// max intervals, then stretched to the publisher's hints.
+func nextFetch(ctx context.Context, s *state, f database.Feed, now time.Time) time.Time {
+	interval := default_interval
+	dates, err := s.db.GetPostDates(ctx,
+		database.GetPostDatesParams{
+			FeedID: f.ID,
+			Limit:  cadence_posts,
+		})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gator: %v\n", err)
+	} else if c, ok := cadence(dates); ok {
+		interval = c
+	}
+
+	interval = max(interval, s.cfg.MinInterval())
+	interval = min(interval, s.cfg.MaxInterval())
+	interval = max(interval, time.Duration(f.MinInterval)*time.Second)
+
+	return skipForward(now.Add(interval), f.SkipHours, f.SkipDays)
+}
+
+// cadence returns the median time between the posts published at dates,
+// which must be sorted newest first.
+func cadence(dates []sql.NullTime) (time.Duration, bool) {
+	var gaps []time.Duration
+	for i := 1; i < len(dates); i++ {
+		if gap := dates[i-1].Time.Sub(dates[i].Time); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+	if len(gaps) < 2 {
+		return 0, false
+	}
+
+	slices.Sort(gaps)
+	return gaps[len(gaps)/2], true
+}
+
+// skipForward moves t to the start of the first hour that isn't in the
+// skip_hours and skip_days masks.
+func skipForward(t time.Time, skip_hours, skip_days int32) time.Time {
+	// a week of hours covers every combination of the masks
+	for range 7 * 24 {
+		hour_bit, day_bit := skipBits(t)
+		if skip_hours&hour_bit == 0 && skip_days&day_bit == 0 {
+			break
+		}
+		t = t.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// backoff returns how long to wait before fetching a feed that failed
+// failures times in a row, starting at five minutes and doubling with
+// every failure up to a day.
+func backoff(failures int32) time.Duration {
+	d := 5 * time.Minute
+	for i := int32(1); i < failures && d < 24*time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, 24*time.Hour)
+}
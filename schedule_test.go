@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMinInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Channel
+		want time.Duration
+	}{
+		{"no hints", Channel{}, 0},
+		{"ttl", Channel{TTL: " 60 "}, time.Hour},
+		{"bad ttl", Channel{TTL: "soon"}, 0},
+		{"negative ttl", Channel{TTL: "-5"}, 0},
+		{"update period", Channel{UpdatePeriod: "hourly"}, time.Hour},
+		{"update frequency", Channel{UpdatePeriod: "daily", UpdateFrequency: "4"}, 6 * time.Hour},
+		{"bad update frequency", Channel{UpdatePeriod: "Daily", UpdateFrequency: "0"}, 24 * time.Hour},
+		{"unknown update period", Channel{UpdatePeriod: "fortnightly"}, 0},
+		{"longest hint wins", Channel{TTL: "180", UpdatePeriod: "hourly"}, 3 * time.Hour},
+		{"capped at a day", Channel{UpdatePeriod: "weekly"}, 24 * time.Hour},
+		{"capped ttl", Channel{TTL: "10080"}, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.minInterval(); got != tt.want {
+			t.Errorf("%s: minInterval() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSkipMasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		c         Channel
+		wantHours int32
+		wantDays  int32
+	}{
+		{"none", Channel{}, 0, 0},
+		{"hours", Channel{SkipHours: []string{"0", " 1 ", "23"}}, 1<<0 | 1<<1 | 1<<23, 0},
+		{"hour 24 is midnight", Channel{SkipHours: []string{"24"}}, 1 << 0, 0},
+		{"bad hours", Channel{SkipHours: []string{"25", "-1", "noon"}}, 0, 0},
+		{"days", Channel{SkipDays: []string{"Saturday", " sunday "}}, 0, 1<<time.Saturday | 1<<time.Sunday},
+		{"bad days", Channel{SkipDays: []string{"Sat", "Caturday"}}, 0, 0},
+		{"every day", Channel{SkipDays: []string{
+			"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday",
+		}}, 0, 0},
+	}
+
+	every_hour := Channel{}
+	for h := 1; h <= 24; h++ {
+		every_hour.SkipHours = append(every_hour.SkipHours, strconv.Itoa(h))
+	}
+	tests = append(tests, struct {
+		name      string
+		c         Channel
+		wantHours int32
+		wantDays  int32
+	}{"every hour", every_hour, 0, 0})
+
+	for _, tt := range tests {
+		hours, days := tt.c.skipMasks()
+		if hours != tt.wantHours || days != tt.wantDays {
+			t.Errorf("%s: skipMasks() = %b, %b, want %b, %b",
+				tt.name, hours, days, tt.wantHours, tt.wantDays)
+		}
+	}
+}
+
+func TestSkipForward(t *testing.T) {
+	// a Wednesday
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 5, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		t     time.Time
+		hours int32
+		days  int32
+		want  time.Time
+	}{
+		{"nothing skipped", at(1, 10, 30), 0, 0, at(1, 10, 30)},
+		{"other hours skipped", at(1, 10, 30), 1 << 9, 0, at(1, 10, 30)},
+		{"skipped hour", at(1, 10, 30), 1 << 10, 0, at(1, 11, 0)},
+		{"skipped hours", at(1, 22, 30), 1<<22 | 1<<23 | 1<<0, 0, at(2, 1, 0)},
+		{"skipped day", at(1, 10, 30), 0, 1 << time.Wednesday, at(2, 0, 0)},
+		{"skipped weekend", at(4, 10, 30), 0, 1<<time.Saturday | 1<<time.Sunday, at(6, 0, 0)},
+		{"skipped day and hour", at(1, 10, 30), 1 << 0, 1 << time.Wednesday, at(2, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		if got := skipForward(tt.t, tt.hours, tt.days); !got.Equal(tt.want) {
+			t.Errorf("%s: skipForward(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestCadence(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	// dates returns the posting dates ago before start, newest first
+	dates := func(ago ...time.Duration) []sql.NullTime {
+		var d []sql.NullTime
+		for _, a := range ago {
+			d = append(d, sql.NullTime{Time: start.Add(-a), Valid: true})
+		}
+		return d
+	}
+
+	tests := []struct {
+		name   string
+		dates  []sql.NullTime
+		want   time.Duration
+		wantOK bool
+	}{
+		{"no posts", nil, 0, false},
+		{"one post", dates(0), 0, false},
+		{"one gap", dates(0, time.Hour), 0, false},
+		{"regular", dates(0, time.Hour, 2*time.Hour, 3*time.Hour), time.Hour, true},
+		{"median", dates(0, time.Hour, 3*time.Hour, 10*time.Hour), 2 * time.Hour, true},
+		{"same dates ignored", dates(0, 0, 0, time.Hour), 0, false},
+		{"out of order ignored", dates(0, 2*time.Hour, time.Hour, 3*time.Hour, 5*time.Hour), 2 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := cadence(tt.dates)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: cadence() = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{0, 5 * time.Minute},
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{3, 20 * time.Minute},
+		{5, 80 * time.Minute},
+		{9, 1280 * time.Minute},
+		{10, 24 * time.Hour},
+		{1000, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
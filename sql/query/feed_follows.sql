@@ -2,9 +2,9 @@
 WITH
 	new_feed_follow AS (
 		INSERT INTO
-			feed_follows (id, created_at, updated_at, feed_id, user_id)
+			feed_follows (id, created_at, updated_at, feed_id, user_id, folder_id)
 		VALUES
-			($1, $2, $3, $4, $5)
+			($1, $2, $3, $4, $5, $6)
 		RETURNING
 			*
 	)
@@ -19,15 +19,27 @@ FROM
 ;
 
 -- name: GetFeedFollowsForUser :many
+-- The follows of a user grouped by folder, the ones outside of any folder
+-- first. Only the follows in folder_id when it's not null.
 SELECT
 	feed_follows.*,
-	feeds.name AS feed,
-	feeds.url  AS url
+	feeds.name     AS feed,
+	feeds.url      AS url,
+	feeds.site_url AS site_url,
+	folders.name   AS folder
 FROM
 	feed_follows
 	INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+	LEFT JOIN folders ON feed_follows.folder_id = folders.id
 WHERE
-	feed_follows.user_id = $1
+	feed_follows.user_id = sqlc.arg(user_id)
+	AND (
+		sqlc.narg(folder_id)::UUID IS NULL
+		OR feed_follows.folder_id = sqlc.narg(folder_id)
+	)
+ORDER BY
+	folders.name ASC NULLS FIRST,
+	feeds.name
 ;
 
 -- name: DeleteFeedFollow :exec
@@ -35,3 +47,37 @@ DELETE FROM feed_follows
 WHERE
 	user_id = $1 AND feed_id = $2
 ;
+
+-- name: MoveFeedFollows :exec
+-- Moves the follows of one feed onto another, users who already follow both
+-- keep the follow they have.
+UPDATE feed_follows
+SET
+	feed_id    = sqlc.arg(to_feed_id),
+	updated_at = sqlc.arg(updated_at)
+WHERE
+	feed_follows.feed_id = sqlc.arg(from_feed_id)
+	AND feed_follows.user_id NOT IN (
+		SELECT
+			followed.user_id
+		FROM
+			feed_follows AS followed
+		WHERE
+			followed.feed_id = sqlc.arg(to_feed_id)
+	)
+;
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET
+	folder_id  = $1,
+	updated_at = $2
+WHERE
+	user_id = $3 AND feed_id = $4
+;
+
+-- name: DeleteFeedFollowsForUser :exec
+DELETE FROM feed_follows
+WHERE
+	user_id = $1
+;
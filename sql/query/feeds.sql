@@ -1,17 +1,25 @@
 -- name: CreateFeed :one
 INSERT INTO
-	feeds (id, created_at, updated_at, name, url, user_id)
+	feeds (id, created_at, updated_at, name, url, url_key, user_id)
 VAlUES
-	($1, $2, $3, $4, $5, $6)
+	($1, $2, $3, $4, $5, $6, $7)
 RETURNING
 	*
 ;
 
 -- name: GetFeeds :many
 SELECT
-	feeds.name AS name,
-	feeds.url  AS url,
-	users.name AS creator
+	feeds.name          AS name,
+	feeds.url           AS url,
+	users.name          AS creator,
+	feeds.failure_count AS failure_count,
+	feeds.last_error    AS last_error,
+	feeds.last_error_at AS last_error_at,
+	feeds.disabled_at   AS disabled_at,
+	feeds.next_fetch_at AS next_fetch_at,
+	feeds.moved_from    AS moved_from,
+	feeds.moved_at      AS moved_at,
+	feeds.site_url      AS site_url
 FROM
 	feeds
 	JOIN users ON feeds.user_id = users.id
@@ -23,25 +31,230 @@ SELECT
 FROM
 	feeds
 WHERE
-	url = $1
+	url_key = $1
+;
+
+-- name: SetFeedCacheHeaders :exec
+UPDATE feeds
+SET
+	etag          = $1,
+	last_modified = $2,
+	updated_at    = $3
+WHERE
+	id = $4
+;
+
+-- name: ClaimDueFeeds :many
+-- Claims up to max_feeds enabled feeds whose next_fetch_at has come, in the
+-- order they became due. Claimed feeds are leased until lease_until, rows
+-- locked or leased by another aggregator are skipped, and a lease that
+-- expired without being released (a crashed aggregator) is claimable again.
+UPDATE feeds
+SET
+	lease_expires_at = sqlc.arg(lease_until)::TIMESTAMP,
+	updated_at       = sqlc.arg(now)::TIMESTAMP
+WHERE
+	id IN (
+		SELECT
+			id
+		FROM
+			feeds
+		WHERE
+			disabled_at IS NULL
+			AND (
+				lease_expires_at IS NULL
+				OR lease_expires_at <= sqlc.arg(now)::TIMESTAMP
+			)
+			AND (
+				next_fetch_at IS NULL
+				OR next_fetch_at <= sqlc.arg(now)::TIMESTAMP
+			)
+		ORDER BY
+			next_fetch_at ASC NULLS FIRST
+		LIMIT
+			sqlc.arg(max_feeds)
+		FOR UPDATE SKIP LOCKED
+	)
+RETURNING
+	*
 ;
 
 -- name: MarkFeedFetched :exec
+-- Releases the lease on a successfully fetched feed.
+UPDATE feeds
+SET
+	last_fetched_at  = $1,
+	next_fetch_at    = $2,
+	failure_count    = 0,
+	lease_expires_at = NULL,
+	updated_at       = $3
+WHERE
+	id = $4
+;
+
+-- name: MarkFeedFailed :one
+-- Releases the lease on a feed whose fetch failed.
 UPDATE feeds
 SET
-	last_fetched_at = $1,
-	updated_at      = $2
+	last_fetched_at  = $1,
+	next_fetch_at    = $2,
+	last_error       = $3,
+	last_error_at    = $1,
+	failure_count    = failure_count + 1,
+	lease_expires_at = NULL,
+	updated_at       = $4
+WHERE
+	id = $5
+RETURNING
+	failure_count
+;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET
+	disabled_at = $1,
+	updated_at  = $2
 WHERE
 	id = $3
 ;
 
--- name: GetNextFeed :one
-SELECT
+-- name: EnableFeed :execrows
+UPDATE feeds
+SET
+	disabled_at   = NULL,
+	next_fetch_at = NULL,
+	failure_count = 0,
+	updated_at    = $1
+WHERE
+	url_key = $2
+;
+
+-- name: ReleaseFeed :exec
+UPDATE feeds
+SET
+	lease_expires_at = NULL
+WHERE
+	id = $1
+;
+
+-- name: DeferFeed :exec
+-- Releases the lease on a feed that wasn't fetched, it's due again at
+-- next_fetch_at.
+UPDATE feeds
+SET
+	next_fetch_at    = $1,
+	lease_expires_at = NULL,
+	updated_at       = $2
+WHERE
+	id = $3
+;
+
+-- name: ClaimFeed :one
+-- Claims the feed with url_key whatever its schedule, unless another
+-- aggregator holds its lease.
+UPDATE feeds
+SET
+	lease_expires_at = sqlc.arg(lease_until)::TIMESTAMP,
+	updated_at       = sqlc.arg(now)::TIMESTAMP
+WHERE
+	url_key = sqlc.arg(url_key)
+	AND (
+		lease_expires_at IS NULL
+		OR lease_expires_at <= sqlc.arg(now)::TIMESTAMP
+	)
+RETURNING
 	*
-FROM
-	feeds
-ORDER BY
-	last_fetched_at ASC NULLS FIRST
-LIMIT
-	1
+;
+
+-- name: SetFeedSchedule :exec
+UPDATE feeds
+SET
+	min_interval = $1,
+	skip_hours   = $2,
+	skip_days    = $3,
+	updated_at   = $4
+WHERE
+	id = $5
+;
+
+-- name: MoveFeed :exec
+UPDATE feeds
+SET
+	url        = $1,
+	url_key    = $2,
+	moved_from = $3,
+	moved_at   = $4,
+	updated_at = $5
+WHERE
+	id = $6
+;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE
+	id = $1
+;
+
+-- name: SetFeedSiteUrl :exec
+UPDATE feeds
+SET
+	site_url   = $1,
+	updated_at = $2
+WHERE
+	id = $3
+;
+
+-- name: GetFeedStats :one
+-- What deleting a feed takes with it.
+SELECT
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feed_follows
+		WHERE
+			feed_follows.feed_id = sqlc.arg(id)
+	) AS followers,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			posts
+		WHERE
+			posts.feed_id = sqlc.arg(id)
+	) AS posts
+;
+
+-- name: RenameFeed :exec
+UPDATE feeds
+SET
+	name       = $1,
+	updated_at = $2
+WHERE
+	id = $3
+;
+
+-- name: SetFeedUrl :exec
+-- Points a feed at a new url, what gator learnt fetching the old one is
+-- forgotten and the feed is due right away.
+UPDATE feeds
+SET
+	url           = $1,
+	url_key       = $2,
+	etag          = NULL,
+	last_modified = NULL,
+	last_error    = NULL,
+	last_error_at = NULL,
+	failure_count = 0,
+	disabled_at   = NULL,
+	next_fetch_at = NULL,
+	updated_at    = $3
+WHERE
+	id = $4
+;
+
+-- name: DeleteFeedsForUser :exec
+DELETE FROM feeds
+WHERE
+	user_id = $1
 ;
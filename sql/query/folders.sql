@@ -0,0 +1,53 @@
+-- name: CreateFolder :one
+INSERT INTO
+	folders (id, created_at, updated_at, name, user_id)
+VALUES
+	($1, $2, $3, $4, $5)
+RETURNING
+	*
+;
+
+-- name: GetFolder :one
+SELECT
+	*
+FROM
+	folders
+WHERE
+	user_id = $1 AND name = $2
+;
+
+-- name: GetFoldersForUser :many
+SELECT
+	folders.*,
+	COUNT(feed_follows.id) AS feeds
+FROM
+	folders
+	LEFT JOIN feed_follows ON feed_follows.folder_id = folders.id
+WHERE
+	folders.user_id = $1
+GROUP BY
+	folders.id
+ORDER BY
+	folders.name
+;
+
+-- name: RenameFolder :execrows
+UPDATE folders
+SET
+	name       = sqlc.arg(new_name),
+	updated_at = sqlc.arg(updated_at)
+WHERE
+	user_id = sqlc.arg(user_id) AND name = sqlc.arg(name)
+;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE
+	user_id = $1 AND name = $2
+;
+
+-- name: DeleteFoldersForUser :exec
+DELETE FROM folders
+WHERE
+	user_id = $1
+;
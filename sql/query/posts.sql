@@ -1,4 +1,4 @@
--- name: CreatePost :exec
+-- name: UpsertPost :one
 INSERT INTO
 	posts (
 		id,
@@ -8,13 +8,60 @@ INSERT INTO
 		url,
 		description,
 		published_at,
+		published_at_estimated,
+		guid,
 		feed_id
 	)
 VALUES
-	($1, $2, $3, $4, $5, $6, $7, $8)
+	($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET
+	updated_at             = EXCLUDED.updated_at,
+	title                  = EXCLUDED.title,
+	url                    = EXCLUDED.url,
+	description            = EXCLUDED.description,
+	published_at           = CASE
+		WHEN EXCLUDED.published_at_estimated THEN posts.published_at
+		ELSE EXCLUDED.published_at
+	END,
+	published_at_estimated = posts.published_at_estimated
+		AND EXCLUDED.published_at_estimated
+WHERE
+	posts.title IS DISTINCT FROM EXCLUDED.title
+	OR posts.url IS DISTINCT FROM EXCLUDED.url
+	OR posts.description IS DISTINCT FROM EXCLUDED.description
+	OR (
+		NOT EXCLUDED.published_at_estimated
+		AND posts.published_at IS DISTINCT FROM EXCLUDED.published_at
+	)
+RETURNING
+	(xmax = 0)::BOOLEAN AS inserted
+;
+
+-- name: AdoptPost :exec
+-- Gives the post stored under its link, as every post was before posts had a
+-- guid, the guid it's known by now.
+UPDATE posts
+SET
+	guid       = sqlc.arg(guid),
+	updated_at = sqlc.arg(updated_at)
+WHERE
+	posts.feed_id = sqlc.arg(feed_id)
+	AND posts.guid = sqlc.arg(url)
+	AND posts.url = sqlc.arg(url)
+	AND NOT EXISTS (
+		SELECT
+			1
+		FROM
+			posts AS adopted
+		WHERE
+			adopted.feed_id = sqlc.arg(feed_id)
+			AND adopted.guid = sqlc.arg(guid)
+	)
 ;
 
 -- name: GetPostsUser :many
+-- Only the posts of the feeds in folder_id when it's not null.
 SELECT
 	*
 FROM
@@ -26,9 +73,49 @@ WHERE
 		FROM
 			feed_follows
 		WHERE
-			user_id = $1
+			user_id = sqlc.arg(user_id)
+			AND (
+				sqlc.narg(folder_id)::UUID IS NULL
+				OR folder_id = sqlc.narg(folder_id)
+			)
 	)
 ORDER BY published_at DESC
+LIMIT
+	sqlc.arg('limit')
+;
+
+-- name: GetPostDates :many
+-- The most recent publication dates of a feed's posts, estimated ones are
+-- left out since they're only the time gator first saw the post.
+SELECT
+	published_at
+FROM
+	posts
+WHERE
+	feed_id = $1
+	AND published_at IS NOT NULL
+	AND NOT published_at_estimated
+ORDER BY
+	published_at DESC
 LIMIT
 	$2
 ;
+
+-- name: MovePosts :exec
+-- Moves the posts of one feed onto another, posts the other feed already has
+-- are left behind.
+UPDATE posts
+SET
+	feed_id    = sqlc.arg(to_feed_id),
+	updated_at = sqlc.arg(updated_at)
+WHERE
+	posts.feed_id = sqlc.arg(from_feed_id)
+	AND posts.guid NOT IN (
+		SELECT
+			kept.guid
+		FROM
+			posts AS kept
+		WHERE
+			kept.feed_id = sqlc.arg(to_feed_id)
+	)
+;
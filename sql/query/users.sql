@@ -26,3 +26,58 @@ SELECT
 FROM
 	users
 ;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE
+	id = $1
+;
+
+-- name: RenameUser :execrows
+UPDATE users
+SET
+	name       = sqlc.arg(new_name),
+	updated_at = sqlc.arg(updated_at)
+WHERE
+	name = sqlc.arg(name)
+;
+
+-- name: GetUserStats :one
+-- What deleting a user takes with it, the feeds they added go too along with
+-- the follows other users have of them.
+SELECT
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feeds
+		WHERE
+			feeds.user_id = sqlc.arg(id)
+	) AS feeds,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feed_follows
+		WHERE
+			feed_follows.user_id = sqlc.arg(id)
+	) AS follows,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			folders
+		WHERE
+			folders.user_id = sqlc.arg(id)
+	) AS folders,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			feed_follows
+			INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+		WHERE
+			feeds.user_id = sqlc.arg(id)
+			AND feed_follows.user_id <> sqlc.arg(id)
+	) AS other_follows
+;
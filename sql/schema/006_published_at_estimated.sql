@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN published_at_estimated BOOLEAN NOT NULL DEFAULT FALSE
+;
+
+UPDATE posts
+SET
+	published_at           = created_at,
+	published_at_estimated = TRUE
+WHERE
+	published_at IS NULL
+;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN published_at_estimated
+;
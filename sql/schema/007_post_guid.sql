@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN guid TEXT
+;
+
+UPDATE posts
+SET
+	guid = url
+;
+
+ALTER TABLE posts
+ALTER COLUMN guid SET NOT NULL,
+DROP CONSTRAINT posts_url_key,
+ADD UNIQUE (feed_id, guid)
+;
+
+-- +goose Down
+DELETE FROM posts a
+USING
+	posts b
+WHERE
+	a.url = b.url AND a.ctid > b.ctid
+;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_feed_id_guid_key,
+ADD UNIQUE (url),
+DROP COLUMN guid
+;
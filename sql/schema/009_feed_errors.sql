@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN last_error    TEXT,
+ADD COLUMN last_error_at TIMESTAMP,
+ADD COLUMN failure_count INTEGER NOT NULL DEFAULT 0
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_error,
+DROP COLUMN last_error_at,
+DROP COLUMN failure_count
+;
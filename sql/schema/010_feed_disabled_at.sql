@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN disabled_at TIMESTAMP
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN disabled_at
+;
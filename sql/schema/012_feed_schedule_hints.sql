@@ -0,0 +1,15 @@
+-- +goose Up
+-- min_interval is in seconds, skip_hours and skip_days are bit sets of the
+-- GMT hours (bit 0 is midnight) and days (bit 0 is Sunday) not to fetch in.
+ALTER TABLE feeds
+ADD COLUMN min_interval INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN skip_hours   INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN skip_days    INTEGER NOT NULL DEFAULT 0
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN min_interval,
+DROP COLUMN skip_hours,
+DROP COLUMN skip_days
+;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- moved_from is the url the feed had before it last answered with a permanent
+-- redirect, or the url of the feed that was merged into it.
+ALTER TABLE feeds
+ADD COLUMN moved_from TEXT,
+ADD COLUMN moved_at   TIMESTAMP
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN moved_from,
+DROP COLUMN moved_at
+;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- category is the folder the feed was filed under in the reader its follow
+-- was imported from.
+ALTER TABLE feed_follows
+ADD COLUMN category TEXT
+;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN category
+;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- site_url is the website the feed belongs to, as the feed itself links to it.
+ALTER TABLE feeds
+ADD COLUMN site_url TEXT
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN site_url
+;
@@ -0,0 +1,71 @@
+-- +goose Up
+CREATE TABLE folders (
+	id         UUID,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	name       TEXT      NOT NULL,
+	user_id    UUID      NOT NULL,
+	UNIQUE (user_id, name),
+	PRIMARY KEY (id),
+	FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+)
+;
+
+-- deleting a folder keeps its follows, outside of any folder
+ALTER TABLE feed_follows
+ADD COLUMN folder_id UUID,
+ADD FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE SET NULL
+;
+
+-- the categories of imported follows become folders
+INSERT INTO
+	folders (id, created_at, updated_at, name, user_id)
+SELECT
+	gen_random_uuid(),
+	MIN(created_at),
+	MIN(created_at),
+	category,
+	user_id
+FROM
+	feed_follows
+WHERE
+	category IS NOT NULL
+GROUP BY
+	user_id,
+	category
+;
+
+UPDATE feed_follows
+SET
+	folder_id = folders.id
+FROM
+	folders
+WHERE
+	folders.user_id = feed_follows.user_id
+	AND folders.name = feed_follows.category
+;
+
+ALTER TABLE feed_follows
+DROP COLUMN category
+;
+
+-- +goose Down
+ALTER TABLE feed_follows
+ADD COLUMN category TEXT
+;
+
+UPDATE feed_follows
+SET
+	category = folders.name
+FROM
+	folders
+WHERE
+	folders.id = feed_follows.folder_id
+;
+
+ALTER TABLE feed_follows
+DROP COLUMN folder_id
+;
+
+DROP TABLE folders
+;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- url_key is the feed's url in its normalized form without the scheme, the
+-- feed is matched by it but fetched at url. Migration 20 fills it in.
+ALTER TABLE feeds
+ADD COLUMN url_key TEXT
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN url_key
+;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ALTER COLUMN url_key SET NOT NULL,
+ADD UNIQUE (url_key)
+;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP CONSTRAINT feeds_url_key_key,
+ALTER COLUMN url_key DROP NOT NULL
+;
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// tracking_params are query parameters that only tell the publisher where a
+// link was clicked, two urls that differ by them point to the same feed.
+var tracking_params = []string{
+	"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content",
+	"fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga",
+}
+
+// feedURL checks that raw is a url gator can fetch and returns it, trimmed
+// but otherwise as given, along with the key feeds are matched by.
+func feedURL(raw string) (url, key string, err error) {
+	u, err := normalizeURL(raw)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(raw), urlKey(u), nil
+}
+
+// normalizeURL returns the canonical form feed urls are compared in: lower
+// case scheme and host, no default port, no trailing slash, no fragment and
+// no tracking parameters. Feeds are still fetched at the url they were given.
+func normalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("gator: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("gator: '%s' isn't an http(s) url.", raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("gator: '%s' has no host.", raw)
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	// trimmed in its escaped form, so an escaped slash isn't mistaken for
+	// a trailing one
+	escaped := strings.TrimRight(u.EscapedPath(), "/")
+	if path, err := url.PathUnescape(escaped); err == nil {
+		u.Path, u.RawPath = path, escaped
+	}
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	}
+
+	// filter the raw query instead of going through url.Values, the order of
+	// the remaining parameters is kept as is
+	var query []string
+	for _, kv := range strings.Split(u.RawQuery, "&") {
+		if kv == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(kv, "=")
+		if k, err := url.QueryUnescape(key); err == nil && isTrackingParam(k) {
+			continue
+		}
+		query = append(query, kv)
+	}
+	u.RawQuery = strings.Join(query, "&")
+	u.ForceQuery = false
+	u.Fragment, u.RawFragment = "", ""
+
+	return u.String(), nil
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range tracking_params {
+		if key == p {
+			return true
+		}
+	}
+	return false
+}
+
+// urlKey is what two normalized feed urls have in common when they're the
+// same feed, http:// and https:// urls name the same feed.
+func urlKey(u string) string {
+	_, rest, _ := strings.Cut(u, "://")
+	return rest
+}
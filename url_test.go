@@ -0,0 +1,114 @@
+package main
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/feed", "https://example.com/feed"},
+		{"  https://example.com/feed\n", "https://example.com/feed"},
+		// scheme and host case, default ports
+		{"HTTPS://Example.COM/Feed", "https://example.com/Feed"},
+		{"https://example.com:443/feed", "https://example.com/feed"},
+		{"http://example.com:80/feed", "http://example.com/feed"},
+		{"http://example.com:443/feed", "http://example.com:443/feed"},
+		{"http://[::1]:80/feed", "http://[::1]/feed"},
+		{"http://[::1]:8080/feed", "http://[::1]:8080/feed"},
+		// trailing slashes, escaped ones are part of the path
+		{"https://example.com/feed/", "https://example.com/feed"},
+		{"https://example.com/feed//", "https://example.com/feed"},
+		{"https://example.com", "https://example.com/"},
+		{"https://example.com/", "https://example.com/"},
+		{"https://example.com/a%2F/", "https://example.com/a%2F"},
+		{"https://example.com/a%2F", "https://example.com/a%2F"},
+		// fragments and tracking parameters
+		{"https://example.com/feed#top", "https://example.com/feed"},
+		{"https://example.com/feed?", "https://example.com/feed"},
+		{"https://example.com/feed?utm_source=x&utm_medium=y", "https://example.com/feed"},
+		{"https://example.com/feed?b=2&UTM_Campaign=x&a=1", "https://example.com/feed?b=2&a=1"},
+		{"https://example.com/feed?fbclid=1&format=rss", "https://example.com/feed?format=rss"},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeURL(tt.in)
+		if err != nil {
+			t.Errorf("normalizeURL(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeURLInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"example.com/feed",
+		"ftp://example.com/feed",
+		"file:///etc/passwd",
+		"https:///feed",
+		"https://exa mple.com/feed",
+	} {
+		if got, err := normalizeURL(in); err == nil {
+			t.Errorf("normalizeURL(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestFeedURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantURL string
+		wantKey string
+	}{
+		// the url is kept as given, only the key is normalized
+		{"https://example.com/feed/", "https://example.com/feed/", "example.com/feed"},
+		{" HTTP://Example.com/feed ", "HTTP://Example.com/feed", "example.com/feed"},
+		{"https://example.com/a%2F/", "https://example.com/a%2F/", "example.com/a%2F"},
+		{"https://example.com/?utm_source=x", "https://example.com/?utm_source=x", "example.com/"},
+	}
+
+	for _, tt := range tests {
+		url, key, err := feedURL(tt.in)
+		if err != nil {
+			t.Errorf("feedURL(%q): %v", tt.in, err)
+			continue
+		}
+		if url != tt.wantURL || key != tt.wantKey {
+			t.Errorf("feedURL(%q) = %q, %q, want %q, %q", tt.in, url, key, tt.wantURL, tt.wantKey)
+		}
+	}
+}
+
+func TestFeedURLSameFeed(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"http://x.com/feed", "https://x.com/feed/", true},
+		{"HTTPS://X.com/feed", "https://x.com/feed", true},
+		{"https://x.com:443/feed?utm_source=a", "http://x.com/feed", true},
+		{"https://x.com/feed", "https://x.com/Feed", false},
+		{"https://x.com/feed", "https://www.x.com/feed", false},
+		{"https://x.com/feed?page=1", "https://x.com/feed?page=2", false},
+		{"https://x.com:8443/feed", "https://x.com/feed", false},
+	}
+
+	for _, tt := range tests {
+		_, key_a, err := feedURL(tt.a)
+		if err != nil {
+			t.Fatalf("feedURL(%q): %v", tt.a, err)
+		}
+		_, key_b, err := feedURL(tt.b)
+		if err != nil {
+			t.Fatalf("feedURL(%q): %v", tt.b, err)
+		}
+		if (key_a == key_b) != tt.same {
+			t.Errorf("keys of %q and %q are %q and %q, want same = %v",
+				tt.a, tt.b, key_a, key_b, tt.same)
+		}
+	}
+}